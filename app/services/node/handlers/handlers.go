@@ -11,6 +11,8 @@ import (
 	"github.com/ardanlabs/blockchain/app/services/node/handlers/debug/checkgrp"
 	v1 "github.com/ardanlabs/blockchain/app/services/node/handlers/v1"
 	"github.com/ardanlabs/blockchain/business/web/v1/mid"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
@@ -19,6 +21,8 @@ import (
 type MuxConfig struct {
 	Shutdown chan os.Signal
 	Log      *zap.SugaredLogger
+	State    *state.State
+	Evts     *events.Events
 }
 
 // PublicMux constructs a http.Handler with all application routes defined.
@@ -44,7 +48,9 @@ func PublicMux(cfg MuxConfig) http.Handler {
 
 	// Load the v1 routes.
 	v1.PublicRoutes(app, v1.Config{
-		Log: cfg.Log,
+		Log:   cfg.Log,
+		State: cfg.State,
+		Evts:  cfg.Evts,
 	})
 
 	return app
@@ -73,7 +79,8 @@ func PrivateMux(cfg MuxConfig) http.Handler {
 
 	// Load the v1 routes.
 	v1.PrivateRoutes(app, v1.Config{
-		Log: cfg.Log,
+		Log:   cfg.Log,
+		State: cfg.State,
 	})
 
 	return app
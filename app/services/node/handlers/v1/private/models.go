@@ -0,0 +1,78 @@
+package private
+
+import (
+	"math/big"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// nodeTx is the block transaction a peer shares with this node. The JSON
+// form matches database.BlockTx so peers can send the value directly.
+type nodeTx struct {
+	ChainID   uint16             `json:"chain_id"`
+	Nonce     uint64             `json:"nonce" validate:"required"`
+	FromID    database.AccountID `json:"from_id" validate:"required"`
+	ToID      database.AccountID `json:"to_id" validate:"required"`
+	Value     uint64             `json:"value"`
+	Tip       uint64             `json:"tip"`
+	Data      []byte             `json:"data"`
+	R         *big.Int           `json:"r" validate:"required"`
+	S         *big.Int           `json:"s" validate:"required"`
+	V         *big.Int           `json:"v" validate:"required"`
+	TimeStamp uint64             `json:"timestamp" validate:"required"`
+	GasPrice  uint64             `json:"gas_price"`
+	GasUnit   uint64             `json:"gas_unit"`
+}
+
+func (nt nodeTx) toBlockTx() database.BlockTx {
+	return database.BlockTx{
+		SignedTx: database.SignedTx{
+			Tx: database.Tx{
+				ChainID: nt.ChainID,
+				Nonce:   nt.Nonce,
+				FromID:  nt.FromID,
+				ToID:    nt.ToID,
+				Value:   nt.Value,
+				Tip:     nt.Tip,
+				Data:    nt.Data,
+			},
+			R: nt.R,
+			S: nt.S,
+			V: nt.V,
+		},
+		TimeStamp: nt.TimeStamp,
+		GasPrice:  nt.GasPrice,
+		GasUnit:   nt.GasUnit,
+	}
+}
+
+// proposal is the block a peer proposes to be the next block in the chain.
+// The JSON form matches database.BlockData.
+type proposal struct {
+	Hash   string               `json:"hash" validate:"required,hexadecimal,len=66"`
+	Header database.BlockHeader `json:"block"`
+	Trans  []nodeTx             `json:"trans" validate:"required,min=1,dive"`
+}
+
+func (p proposal) toBlockData() database.BlockData {
+	trans := make([]database.BlockTx, len(p.Trans))
+	for i, tx := range p.Trans {
+		trans[i] = tx.toBlockTx()
+	}
+
+	return database.BlockData{
+		Hash:   p.Hash,
+		Header: p.Header,
+		Trans:  trans,
+	}
+}
+
+// submitPeer is the host information a node sends to announce itself.
+type submitPeer struct {
+	Host string `json:"host" validate:"required,hostname_port"`
+}
+
+func (sp submitPeer) toPeer() peer.Peer {
+	return peer.New(sp.Host)
+}
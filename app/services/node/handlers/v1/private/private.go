@@ -3,15 +3,28 @@ package private
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 
+	"github.com/ardanlabs/blockchain/business/sys/validate"
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
 	"github.com/ardanlabs/blockchain/foundation/web"
 	"go.uber.org/zap"
 )
 
+// maxBlocksPerRequest is the largest range of blocks a peer can ask for in a
+// single call. Peers needing more blocks page through the range.
+const maxBlocksPerRequest = 100
+
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log *zap.SugaredLogger
+	Log   *zap.SugaredLogger
+	State *state.State
 }
 
 // Sample just provides a starting point for the class.
@@ -24,3 +37,206 @@ func (h Handlers) Sample(ctx context.Context, w http.ResponseWriter, r *http.Req
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// Status returns the current status of the node.
+func (h Handlers) Status(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	latestBlock := h.State.LatestBlock()
+
+	status := peer.PeerStatus{
+		GenesisHash:       h.State.GenesisHash(),
+		LatestBlockHash:   latestBlock.Hash(),
+		LatestBlockNumber: latestBlock.Header.Number,
+		KnownPeers:        h.State.KnownExternalPeers(),
+	}
+
+	return web.Respond(ctx, w, status, http.StatusOK)
+}
+
+// Mempool returns the set of uncommitted transactions.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	txs := h.State.QueryMempool()
+	return web.Respond(ctx, w, txs, http.StatusOK)
+}
+
+// BlocksByNumber returns all the blocks based on the specified to/from values.
+// The to value can be "latest" to ask for everything up to the latest block.
+// At most maxBlocksPerRequest blocks are returned in a single call.
+func (h Handlers) BlocksByNumber(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	from, err := strconv.ParseUint(web.Param(r, "from"), 10, 64)
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("invalid from value: %w", err), http.StatusBadRequest)
+	}
+
+	latest := h.State.LatestBlock().Header.Number
+
+	if from == 0 {
+		return v1.NewRequestError(errors.New("from value must start at block 1"), http.StatusBadRequest)
+	}
+
+	to := latest
+	if toStr := web.Param(r, "to"); toStr != "latest" {
+		to, err = strconv.ParseUint(toStr, 10, 64)
+		if err != nil {
+			return v1.NewRequestError(fmt.Errorf("invalid to value: %w", err), http.StatusBadRequest)
+		}
+
+		if from > to {
+			return v1.NewRequestError(fmt.Errorf("from value %d is greater than to value %d", from, to), http.StatusBadRequest)
+		}
+	}
+
+	if to-from >= maxBlocksPerRequest {
+		to = from + maxBlocksPerRequest - 1
+	}
+	if to > latest {
+		to = latest
+	}
+
+	if from > to {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	blocks, err := h.State.QueryBlocksByNumber(from, to)
+	if err != nil {
+		return err
+	}
+
+	blockData := make([]database.BlockData, len(blocks))
+	for i, block := range blocks {
+		blockData[i] = database.NewBlockData(block)
+	}
+
+	return web.Respond(ctx, w, blockData, http.StatusOK)
+}
+
+// ProposeBlock takes a block received from a peer, validates it and
+// if that passes, adds the block to the local blockchain.
+func (h Handlers) ProposeBlock(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := h.checkGenesis(r); err != nil {
+		return err
+	}
+
+	// Decode the JSON in the post call into a block proposal.
+	var prop proposal
+	if err := web.Decode(r, &prop); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := validate.Check(prop); err != nil {
+		return err
+	}
+
+	block, err := database.ToBlock(prop.toBlockData())
+	if err != nil {
+		return v1.NewRequestError(fmt.Errorf("unable to decode block: %w", err), http.StatusBadRequest)
+	}
+
+	if hash := block.Hash(); hash != prop.Hash {
+		return v1.NewRequestError(fmt.Errorf("block hash doesn't match, got %s, exp %s", prop.Hash, hash), http.StatusBadRequest)
+	}
+
+	h.Log.Infow("propose block", "traceid", v.TraceID, "blk", block.Header.Number, "hash", prop.Hash)
+
+	// Ask the state package to validate and apply the block. A block that
+	// fails the consensus rules is refused, not a malformed request.
+	if err := h.State.ProcessProposedBlock(block); err != nil {
+		return v1.NewRequestError(fmt.Errorf("block not accepted: %w", err), http.StatusNotAcceptable)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "accepted",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// SubmitNodeTransaction adds new node transactions to the mempool.
+func (h Handlers) SubmitNodeTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := h.checkGenesis(r); err != nil {
+		return err
+	}
+
+	// Decode the JSON in the post call into a block transaction.
+	var ntx nodeTx
+	if err := web.Decode(r, &ntx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := validate.Check(ntx); err != nil {
+		return err
+	}
+
+	tx := ntx.toBlockTx()
+
+	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", tx, "from", tx.FromID, "to", tx.ToID, "value", tx.Value, "tip", tx.Tip)
+
+	// Ask the state package to add this transaction to the mempool.
+	if err := h.State.UpsertNodeTransaction(tx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "added",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// SubmitPeer is called by a node so they can be added to the known peer list.
+func (h Handlers) SubmitPeer(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	if err := h.checkGenesis(r); err != nil {
+		return err
+	}
+
+	var sp submitPeer
+	if err := web.Decode(r, &sp); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := validate.Check(sp); err != nil {
+		return err
+	}
+
+	if h.State.AddKnownPeer(sp.toPeer()) {
+		h.Log.Infow("adding peer", "traceid", v.TraceID, "host", sp.Host)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+	}{
+		Status: "added",
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
+
+// =============================================================================
+
+// checkGenesis validates the node making the request runs with the same
+// genesis as this node. Data and peers from a different chain are refused.
+func (h Handlers) checkGenesis(r *http.Request) error {
+	if hash := r.Header.Get(peer.GenesisHashHeader); hash != h.State.GenesisHash() {
+		return v1.NewRequestError(fmt.Errorf("genesis mismatch, got %q, exp %s", hash, h.State.GenesisHash()), http.StatusNotAcceptable)
+	}
+
+	return nil
+}
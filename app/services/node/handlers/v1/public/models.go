@@ -0,0 +1,140 @@
+package public
+
+import (
+	"math/big"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// The JSON shapes in this file are the ones the Chrome wallet parses.
+
+type act struct {
+	Account database.AccountID `json:"account"`
+	Balance uint64             `json:"balance"`
+	Nonce   uint64             `json:"nonce"`
+}
+
+type actInfo struct {
+	LatestBlock string `json:"latest_block"`
+	Uncommitted int    `json:"uncommitted"`
+	Accounts    []act  `json:"accounts"`
+}
+
+// =============================================================================
+
+type tx struct {
+	Hash        string             `json:"hash"`
+	ChainID     uint16             `json:"chain_id"`
+	Nonce       uint64             `json:"nonce"`
+	FromAccount database.AccountID `json:"from"`
+	To          database.AccountID `json:"to"`
+	Value       uint64             `json:"value"`
+	Tip         uint64             `json:"tip"`
+	Data        []byte             `json:"data"`
+	TimeStamp   uint64             `json:"timestamp"`
+	GasPrice    uint64             `json:"gas_price"`
+	GasUnits    uint64             `json:"gas_units"`
+	Sig         string             `json:"sig"`
+	Proof       []string           `json:"proof,omitempty"`
+	ProofOrder  []int64            `json:"proof_order,omitempty"`
+}
+
+func toTx(blockTx database.BlockTx) tx {
+	return tx{
+		Hash:        blockTx.TxHash(),
+		ChainID:     blockTx.ChainID,
+		Nonce:       blockTx.Nonce,
+		FromAccount: blockTx.FromID,
+		To:          blockTx.ToID,
+		Value:       blockTx.Value,
+		Tip:         blockTx.Tip,
+		Data:        blockTx.Data,
+		TimeStamp:   blockTx.TimeStamp,
+		GasPrice:    blockTx.GasPrice,
+		GasUnits:    blockTx.GasUnit,
+		Sig:         blockTx.SignatureString(),
+	}
+}
+
+// =============================================================================
+
+type block struct {
+	Number        uint64             `json:"number"`
+	PrevBlockHash string             `json:"prev_block_hash"`
+	TimeStamp     uint64             `json:"timestamp"`
+	BeneficiaryID database.AccountID `json:"beneficiary"`
+	Difficulty    uint16             `json:"difficulty"`
+	MiningReward  uint64             `json:"mining_reward"`
+	StateRoot     string             `json:"state_root"`
+	TransRoot     string             `json:"trans_root"`
+	Nonce         uint64             `json:"nonce"`
+	Transactions  []tx               `json:"txs"`
+}
+
+func toBlock(blk database.Block) (block, error) {
+	var txs []tx
+	for _, blockTx := range blk.MerkleTree.Values() {
+		proof, order, err := blk.MerkleTree.Proof(blockTx)
+		if err != nil {
+			return block{}, err
+		}
+
+		t := toTx(blockTx)
+		t.ProofOrder = order
+		for _, hash := range proof {
+			t.Proof = append(t.Proof, hexutil.Encode(hash))
+		}
+
+		txs = append(txs, t)
+	}
+
+	b := block{
+		Number:        blk.Header.Number,
+		PrevBlockHash: blk.Header.PrevBlockHash,
+		TimeStamp:     blk.Header.TimeStamp,
+		BeneficiaryID: blk.Header.BeneficiaryID,
+		Difficulty:    blk.Header.Difficulty,
+		MiningReward:  blk.Header.MiningReward,
+		StateRoot:     blk.Header.StateRoot,
+		TransRoot:     blk.Header.TransRoot,
+		Nonce:         blk.Header.Nonce,
+		Transactions:  txs,
+	}
+
+	return b, nil
+}
+
+// =============================================================================
+
+// submitTx is the signed transaction the wallet submits. The signature is
+// provided in the [R|S|V] format.
+type submitTx struct {
+	ChainID     uint16             `json:"chain_id"`
+	Nonce       uint64             `json:"nonce" validate:"required"`
+	FromAccount database.AccountID `json:"from" validate:"required"`
+	To          database.AccountID `json:"to" validate:"required"`
+	Value       uint64             `json:"value"`
+	Tip         uint64             `json:"tip"`
+	Data        []byte             `json:"data"`
+	V           *big.Int           `json:"v" validate:"required"`
+	R           *big.Int           `json:"r" validate:"required"`
+	S           *big.Int           `json:"s" validate:"required"`
+}
+
+func (st submitTx) toSignedTx() database.SignedTx {
+	return database.SignedTx{
+		Tx: database.Tx{
+			ChainID: st.ChainID,
+			Nonce:   st.Nonce,
+			FromID:  st.FromAccount,
+			ToID:    st.To,
+			Value:   st.Value,
+			Tip:     st.Tip,
+			Data:    st.Data,
+		},
+		V: st.V,
+		R: st.R,
+		S: st.S,
+	}
+}
@@ -3,15 +3,29 @@ package public
 
 import (
 	"context"
+	"errors"
 	"net/http"
+	"time"
 
+	"github.com/ardanlabs/blockchain/business/sys/validate"
+	v1 "github.com/ardanlabs/blockchain/business/web/v1"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/events"
 	"github.com/ardanlabs/blockchain/foundation/web"
+	"github.com/gorilla/websocket"
 	"go.uber.org/zap"
 )
 
+// writeWait is the time allowed to write a message to a websocket client.
+const writeWait = 5 * time.Second
+
 // Handlers manages the set of bar ledger endpoints.
 type Handlers struct {
-	Log *zap.SugaredLogger
+	Log   *zap.SugaredLogger
+	State *state.State
+	WS    websocket.Upgrader
+	Evts  *events.Events
 }
 
 // Sample just provides a starting point for the class.
@@ -24,3 +38,209 @@ func (h Handlers) Sample(ctx context.Context, w http.ResponseWriter, r *http.Req
 
 	return web.Respond(ctx, w, resp, http.StatusOK)
 }
+
+// Events handles a web socket to provide events to a client. Every event
+// sent through the events package is written to the client until the
+// client goes away or the node is shut down.
+func (h Handlers) Events(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Need this to handle CORS on the websocket.
+	h.WS.CheckOrigin = func(r *http.Request) bool { return true }
+
+	// This upgrades the HTTP connection to a websocket connection.
+	c, err := h.WS.Upgrade(w, r, nil)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	// This provides a channel for receiving events from the blockchain.
+	ch := h.Evts.Acquire(v.TraceID)
+	defer h.Evts.Release(v.TraceID)
+
+	// The client is not expected to send anything, but the connection must
+	// be read to process control messages and to know when the client goes
+	// away.
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if _, _, err := c.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Setup a ticker so we can ping the client to keep the connection alive.
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case msg, wd := <-ch:
+
+			// If the channel is closed, release the websocket.
+			if !wd {
+				c.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, "node shutting down"), time.Now().Add(writeWait))
+				return nil
+			}
+
+			c.SetWriteDeadline(time.Now().Add(writeWait))
+			if err := c.WriteMessage(websocket.TextMessage, []byte(msg)); err != nil {
+				return nil
+			}
+
+		case <-ticker.C:
+			if err := c.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(writeWait)); err != nil {
+				return nil
+			}
+
+		case <-done:
+			return nil
+		}
+	}
+}
+
+// Genesis returns the genesis information.
+func (h Handlers) Genesis(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	gen := h.State.Genesis()
+	return web.Respond(ctx, w, gen, http.StatusOK)
+}
+
+// Accounts returns the current balances for all users. If an account is
+// specified, only that account is returned. An account the blockchain has
+// never seen is returned with a zero balance.
+func (h Handlers) Accounts(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountStr := web.Param(r, "account")
+
+	var accounts map[database.AccountID]database.Account
+	switch accountStr {
+	case "":
+		accounts = h.State.Accounts()
+
+	default:
+		accountID, err := database.ToAccountID(accountStr)
+		if err != nil {
+			return v1.NewRequestError(err, http.StatusBadRequest)
+		}
+
+		account, err := h.State.QueryAccount(accountID)
+		if err != nil {
+			if !errors.Is(err, database.ErrUnknownAccount) {
+				return err
+			}
+			account = database.Account{AccountID: accountID}
+		}
+		accounts = map[database.AccountID]database.Account{accountID: account}
+	}
+
+	resp := make([]act, 0, len(accounts))
+	for account, info := range accounts {
+		resp = append(resp, act{
+			Account: account,
+			Balance: info.Balance,
+			Nonce:   info.Nonce,
+		})
+	}
+
+	ai := actInfo{
+		LatestBlock: h.State.LatestBlock().Hash(),
+		Uncommitted: h.State.MempoolLength(),
+		Accounts:    resp,
+	}
+
+	return web.Respond(ctx, w, ai, http.StatusOK)
+}
+
+// BlocksByAccount returns all the blocks and their details that contain a
+// transaction for the specified account. Each transaction carries the merkle
+// proof the wallet uses to verify the transaction is in the block.
+func (h Handlers) BlocksByAccount(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	accountID, err := database.ToAccountID(web.Param(r, "account"))
+	if err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	dbBlocks, err := h.State.QueryBlocksByAccount(accountID)
+	if err != nil {
+		return err
+	}
+
+	if len(dbBlocks) == 0 {
+		return web.Respond(ctx, w, nil, http.StatusNoContent)
+	}
+
+	blocks := make([]block, len(dbBlocks))
+	for i, dbBlock := range dbBlocks {
+		blocks[i], err = toBlock(dbBlock)
+		if err != nil {
+			return err
+		}
+	}
+
+	return web.Respond(ctx, w, blocks, http.StatusOK)
+}
+
+// Mempool returns the set of uncommitted transactions. If an account is
+// specified, only the transactions sent or received by that account are
+// returned.
+func (h Handlers) Mempool(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	acct := database.AccountID(web.Param(r, "account"))
+
+	mempool := h.State.QueryMempool()
+
+	trans := []tx{}
+	for _, blockTx := range mempool {
+		if acct != "" && (blockTx.FromID.Normalize() != acct.Normalize() && blockTx.ToID.Normalize() != acct.Normalize()) {
+			continue
+		}
+
+		trans = append(trans, toTx(blockTx))
+	}
+
+	return web.Respond(ctx, w, trans, http.StatusOK)
+}
+
+// SubmitWalletTransaction adds new transactions to the mempool.
+func (h Handlers) SubmitWalletTransaction(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	v, err := web.GetValues(ctx)
+	if err != nil {
+		return web.NewShutdownError("web value missing from context")
+	}
+
+	// Decode the JSON in the post call into a signed transaction.
+	var stx submitTx
+	if err := web.Decode(r, &stx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	if err := validate.Check(stx); err != nil {
+		return err
+	}
+
+	signedTx := stx.toSignedTx()
+
+	h.Log.Infow("add tran", "traceid", v.TraceID, "sig:nonce", signedTx, "from", signedTx.FromID, "to", signedTx.ToID, "value", signedTx.Value, "tip", signedTx.Tip)
+
+	// Ask the state package to add this transaction to the mempool. Only the
+	// checks are the transaction signature and the recipient account format.
+	// It's up to the wallet to make sure the account has a proper balance and
+	// nonce. Fees will be taken if this transaction is mined into a block.
+	if err := h.State.UpsertWalletTransaction(signedTx); err != nil {
+		return v1.NewRequestError(err, http.StatusBadRequest)
+	}
+
+	resp := struct {
+		Status string `json:"status"`
+		Hash   string `json:"hash"`
+	}{
+		Status: "transactions added to mempool",
+		Hash:   signedTx.TxHash(),
+	}
+
+	return web.Respond(ctx, w, resp, http.StatusOK)
+}
@@ -2,17 +2,30 @@ package main
 
 import (
 	"context"
+	"crypto/ecdsa"
 	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/ardanlabs/blockchain/app/services/node/handlers"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/disk"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/segment"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/worker"
+	"github.com/ardanlabs/blockchain/foundation/events"
+	"github.com/ardanlabs/blockchain/foundation/keystore"
 	"github.com/ardanlabs/blockchain/foundation/logger"
 	"github.com/ardanlabs/conf/v3"
+	"github.com/ethereum/go-ethereum/crypto"
 	"go.uber.org/zap"
 )
 
@@ -56,6 +69,18 @@ func run(log *zap.SugaredLogger) error {
 			PublicHost      string        `conf:"default:0.0.0.0:8080"`
 			PrivateHost     string        `conf:"default:0.0.0.0:9080"`
 		}
+		State struct {
+			Beneficiary    string   `conf:"default:miner1"`
+			AccountsPath   string   `conf:"default:zblock/accounts/"`
+			KeystorePath   string   `conf:"help:directory of encrypted keys used instead of the accounts path"`
+			KeyPassword    string   `conf:"mask"`
+			GenesisPath    string   `conf:"default:zblock/genesis.json"`
+			DBPath         string   `conf:"default:zblock/miner1/"`
+			Storage        string   `conf:"default:disk,help:disk or segment"`
+			SelectStrategy string   `conf:"default:tip"`
+			OriginPeers    []string `conf:"default:0.0.0.0:9080"`
+			Consensus      string   `conf:"default:POW,help:POW or POA"`
+		}
 	}{
 		Version: conf.Version{
 			Build: build,
@@ -95,6 +120,106 @@ func run(log *zap.SugaredLogger) error {
 	}
 	log.Infow("startup", "config", out)
 
+	// =========================================================================
+	// Blockchain Support
+
+	// The events package provides support for sending events to any
+	// connected websocket client.
+	evts := events.New()
+
+	// The blockchain packages accept a function of this signature to allow the
+	// application to log. Events meant for the viewer are also sent to the
+	// websocket clients.
+	ev := func(v string, args ...any) {
+		const websocketPrefix = "viewer:"
+
+		s := fmt.Sprintf(v, args...)
+		log.Infow(s, "traceid", "00000000-0000-0000-0000-000000000000")
+		if strings.HasPrefix(s, websocketPrefix) {
+			evts.Send(s)
+		}
+	}
+
+	// Load the private key file for the configured beneficiary so the
+	// account can receive mining rewards. When a keystore is configured the
+	// key is decrypted with the configured password.
+	var privateKey *ecdsa.PrivateKey
+	switch cfg.State.KeystorePath {
+	case "":
+		path := filepath.Join(cfg.State.AccountsPath, cfg.State.Beneficiary+".ecdsa")
+		if privateKey, err = crypto.LoadECDSA(path); err != nil {
+			return fmt.Errorf("unable to load private key for node: %w", err)
+		}
+	default:
+		path := filepath.Join(cfg.State.KeystorePath, cfg.State.Beneficiary+".json")
+		if privateKey, err = keystore.Load(path, cfg.State.KeyPassword); err != nil {
+			return fmt.Errorf("unable to load private key for node from keystore: %w", err)
+		}
+	}
+	beneficiaryID := database.PublicKeyToAccountID(privateKey.PublicKey)
+	log.Infow("startup", "status", "beneficiary loaded", "name", cfg.State.Beneficiary, "account", beneficiaryID)
+
+	// Load the genesis file to get starting balances for
+	// founders of the block chain.
+	gen, err := genesis.Load(cfg.State.GenesisPath)
+	if err != nil {
+		return fmt.Errorf("loading genesis: %w", err)
+	}
+	log.Infow("startup", "status", "genesis loaded", "path", cfg.State.GenesisPath, "hash", gen.Hash())
+
+	// Construct the storage for the blocks. The disk storage writes each
+	// block to its own file and the segment storage appends every block to
+	// a single file.
+	var storage database.Storage
+	switch cfg.State.Storage {
+	case "disk":
+		storage, err = disk.New(cfg.State.DBPath)
+	case "segment":
+		storage, err = segment.New(cfg.State.DBPath)
+	default:
+		err = fmt.Errorf("unknown storage %q", cfg.State.Storage)
+	}
+	if err != nil {
+		return fmt.Errorf("constructing storage: %w", err)
+	}
+
+	// The peer set starts with the origin peers. More peers are learned
+	// from the status of the known peers as the node runs.
+	peerSet := peer.NewPeerSet()
+	for _, host := range cfg.State.OriginPeers {
+		peerSet.Add(peer.New(host))
+	}
+
+	// The state value represents the blockchain node and manages the
+	// blockchain database and provides an API for application support.
+	// Any stored blocks are replayed on top of the genesis balances to
+	// rebuild the account state.
+	st, err := state.New(state.Config{
+		BeneficiaryID:  beneficiaryID,
+		SignerKey:      privateKey,
+		Host:           cfg.Web.PrivateHost,
+		KnownPeers:     peerSet,
+		Storage:        storage,
+		Genesis:        gen,
+		SelectStrategy: cfg.State.SelectStrategy,
+		Consensus:      cfg.State.Consensus,
+		EvHandler:      ev,
+	})
+	if err != nil {
+		return fmt.Errorf("constructing state: %w", err)
+	}
+	defer st.Shutdown()
+
+	// The worker package implements the different workflows such as mining,
+	// peer status polling and transaction sharing. The Run function will
+	// start a goroutine for each of these workflows and register itself
+	// with the state so the workflows are stopped when the state is shut
+	// down.
+	worker.Run(st, ev)
+
+	latest := st.LatestBlock()
+	log.Infow("startup", "status", "blockchain loaded", "blocks", latest.Header.Number, "hash", latest.Hash())
+
 	// =========================================================================
 	// Start Debug Service
 
@@ -135,6 +260,8 @@ func run(log *zap.SugaredLogger) error {
 	publicMux := handlers.PublicMux(handlers.MuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
+		State:    st,
+		Evts:     evts,
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -162,6 +289,7 @@ func run(log *zap.SugaredLogger) error {
 	privateMux := handlers.PrivateMux(handlers.MuxConfig{
 		Shutdown: shutdown,
 		Log:      log,
+		State:    st,
 	})
 
 	// Construct a server to service the requests against the mux.
@@ -192,6 +320,11 @@ func run(log *zap.SugaredLogger) error {
 		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
 		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
 
+		// Release any websocket connections. The HTTP servers don't track
+		// hijacked connections so they must be closed here.
+		log.Infow("shutdown", "status", "shutdown websocket events")
+		evts.Shutdown()
+
 		// Give outstanding requests a deadline for completion.
 		ctx, cancelPub := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
 		defer cancelPub()
@@ -0,0 +1,96 @@
+// These functions mirror the canonical encoding the node uses for signing and
+// hashing (foundation/blockchain/database/encoding.go). Every value is an RLP
+// list that starts with the encoding version, integers are encoded big endian
+// without leading zeros, accounts as 20 bytes and hashes as 32 bytes. The
+// golden vectors in foundation/blockchain/database/encoding_test.go must be
+// reproduced by these functions.
+
+// encodingVersion is the version of the canonical encoding.
+const encodingVersion = 1;
+
+// blkcorStamp is prepended to the encoded transaction before it's hashed
+// for signing, so signatures are unique to the blkcor blockchain.
+const blkcorStamp = "\x19Blkcor Signed Message:\n";
+
+// blkcorID is added to the recovery id of the signature.
+const blkcorID = 27;
+
+// rlpUint returns the RLP item for an unsigned integer. Zero is encoded as
+// an empty byte string.
+function rlpUint(n) {
+    const bn = ethers.BigNumber.from(n);
+    if (bn.isZero()) {
+        return "0x";
+    }
+    return bn.toHexString();
+}
+
+// rlpBytes returns the RLP item for a byte string. The node marshals the
+// transaction data as base64, a null value is an empty byte string.
+function rlpBytes(data) {
+    if (data == null || data == "") {
+        return "0x";
+    }
+    if (typeof data == "string") {
+        return ethers.utils.hexlify(ethers.utils.base64.decode(data));
+    }
+    return ethers.utils.hexlify(data);
+}
+
+// txFields returns the RLP items for the fields of the transaction that are
+// signed by the sender.
+function txFields(tx) {
+    return [
+        rlpUint(encodingVersion),
+        rlpUint(tx.chain_id),
+        rlpUint(tx.nonce),
+        ethers.utils.getAddress(tx.from),
+        ethers.utils.getAddress(tx.to),
+        rlpUint(tx.value),
+        rlpUint(tx.tip),
+        rlpBytes(tx.data),
+    ];
+}
+
+// encodeTx returns the canonical encoding of the transaction that is signed.
+function encodeTx(tx) {
+    return ethers.utils.RLP.encode(txFields(tx));
+}
+
+// encodeBlockTx returns the canonical encoding of a transaction stored in a
+// block. The signature fields are taken from the 65 byte [R|S|V] signature
+// the node returns with the transaction.
+function encodeBlockTx(tx) {
+    const sig = ethers.utils.arrayify(tx.sig);
+
+    return ethers.utils.RLP.encode(txFields(tx).concat([
+        rlpUint(sig.slice(0, 32)),
+        rlpUint(sig.slice(32, 64)),
+        rlpUint(sig[64]),
+        rlpUint(tx.timestamp),
+        rlpUint(tx.gas_price),
+        rlpUint(tx.gas_units),
+    ]));
+}
+
+// signTx signs the canonical encoding of the transaction with the blkcor
+// stamp and returns the [V|R|S] values the node expects.
+function signTx(privateKey, tx) {
+    const encoded = ethers.utils.arrayify(encodeTx(tx));
+    const stamp = ethers.utils.toUtf8Bytes(blkcorStamp + encoded.length);
+    const digest = ethers.utils.keccak256(ethers.utils.concat([stamp, encoded]));
+
+    const sig = new ethers.utils.SigningKey(privateKey).signDigest(digest);
+
+    return {
+        v: sig.recoveryParam + blkcorID,
+        r: ethers.BigNumber.from(sig.r),
+        s: ethers.BigNumber.from(sig.s),
+    };
+}
+
+// blockTxHash returns the hash of a transaction stored in a block. This is
+// the leaf of the merkle tree of the block.
+function blockTxHash(tx) {
+    return ethers.utils.sha256(encodeBlockTx(tx));
+}
@@ -127,13 +127,21 @@ function connect() {
     socket.addEventListener('message', function (event) {
         const conn = document.getElementById("connected");
 
-        if (event.data.includes("MINING: completed")) {
+        // A block is added when this node mines one or accepts one from a
+        // peer, so this is when the account information changes.
+        if (event.data.includes("BLOCK: added")) {
             conn.className = "connected";
             conn.innerHTML = "CONNECTED";
             load();
             return;
         }
 
+        if (event.data.includes("MINING: completed")) {
+            conn.className = "connected";
+            conn.innerHTML = "CONNECTED";
+            return;
+        }
+
         if (event.data.includes("MINING: running")) {
             conn.className = "mining";
             conn.innerHTML = "MINING...";
@@ -273,7 +281,7 @@ function transactions() {
             var count = 0;
             for (var i = 0; i < resp.length; i++) {
                 for (var j = 0; j < resp[i].txs.length; j++) {
-                    if ((resp[i].txs[j].from == wallet.address) || (resp[i].txs[j].to == wallet.address)) {
+                    if (sameAccount(resp[i].txs[j].from, wallet.address) || sameAccount(resp[i].txs[j].to, wallet.address)) {
                         resp[i].txs[j].proved = false;
 
                         if (validateMerkleProof(resp[i].txs[j], resp[i].trans_root)) {
@@ -294,6 +302,12 @@ function transactions() {
     });
 }
 
+// sameAccount compares two accounts. Accounts are not case sensitive, the
+// node may return an account in a different case than the wallet uses.
+function sameAccount(a, b) {
+    return a.toLowerCase() == b.toLowerCase();
+}
+
 // validateMerkleProof proves cryptographically that the specified transaction
 // is inside the block based on the merkle root value.
 function validateMerkleProof(tx, merkelRoot) {
@@ -328,48 +342,10 @@ function validateMerkleProof(tx, merkelRoot) {
 }
 
 // createTxHash is used by validateMerkleProof to create a hash for the
-// specified transaction to be used to check the merkle proof.
+// specified transaction to be used to check the merkle proof. The node hashes
+// the canonical encoding of the block transaction.
 function createTxHash(tx) {
-
-    // Need to break out the R and S bytes from the signature.
-    const byt = ethers.utils.arrayify(tx.sig);
-    const rSlice = byt.slice(0, 32);
-    const sSlice = byt.slice(32, 64);
-
-    // Create a block transaction for hashing.
-    const blockTx = {
-        chain_id: tx.chain_id,
-        nonce: tx.nonce,
-        from: tx.from,
-        to: tx.to,
-        value: tx.value,
-        tip: tx.tip,
-        data: null,
-        v: byt[64],
-        r: ethers.BigNumber.from(rSlice).toString(),
-        s: ethers.BigNumber.from(sSlice).toString(),
-        timestamp: tx.timestamp,
-        gas_price: tx.gas_price,
-        gas_units: tx.gas_units
-    };
-
-    // Marshal into JSON for the payload.
-    var data = JSON.stringify(blockTx);
-
-    // Go doesn't want big integers to be strings. Removing quotes.
-    data = data.replace('r":"', 'r":');
-    data = data.replace('","s":"', ',"s":');
-    data = data.replace('","ti', ',"ti');
-
-    // Convert the JSON into bytes.
-    var bytes = [];
-    for (var i = 0; i < data.length; ++i) {
-        var code = data.charCodeAt(i);
-        bytes = bytes.concat([code]);
-    }
-
-    // Hash the bytes the same way the node does it.
-    return ethers.utils.sha256(bytes);
+    return blockTxHash(tx);
 }
 
 // mempool makes a request to the node for the current transaction in the mempool.
@@ -386,7 +362,7 @@ function mempool() {
                 msg += JSON.stringify(resp[i], null, 2);
                 count++;
 
-                if (resp[i].from == wallet.address) {
+                if (sameAccount(resp[i].from, wallet.address)) {
 
                     // Check the mempool for what the next nonce should be for this account.
                     const txNonce = Number(resp[i].nonce);
@@ -462,7 +438,7 @@ function submitTran() {
 }
 
 // createTransaction prepares a signed transaction for submission and then
-// through a promise, will call sendTran to physically send the transaction.
+// calls sendTran to physically send the transaction.
 function createTransaction() {
 
     // We got a yes confirmation so we know the values are verified.
@@ -480,45 +456,20 @@ function createTransaction() {
         data: null,
     };
 
-    // Marshal the transaction to a string and convert the string to bytes.
-    const marshal = JSON.stringify(tx);
-    const marshalBytes = ethers.utils.toUtf8Bytes(marshal);
-
-    // Hash the transaction data into a 32 byte array. This will provide
-	// a data length consistency with all transactions.
-    const txHash = ethers.utils.keccak256(marshalBytes);
-    const bytes = ethers.utils.arrayify(txHash);
+    // Sign the canonical encoding of the transaction the same way the node
+    // does it, so the node can recover the from account from the signature.
+    const sig = signTx("0x" + document.getElementById("from").value, tx);
 
-    // Now sign the data. The underlying code will apply the Ardan stamp and
-    // ID to the signature thanks to changes made to the ether.js api.
-    const wallet = new ethers.Wallet(document.getElementById("from").value);
-    signature = wallet.signMessage(bytes);
-
-    // Since everything is built on promises, wait for the signature to
-    // be calculated and then send the transaction to the node.
-    signature.then((sig) => sendTran(tx, sig));
+    sendTran(tx, sig);
 }
 
 // sendTran submits the signed transaction to the node for inclusion.
 function sendTran(tx, sig) {
 
-    // Need to break out the R and S bytes from the signature.
-    const byt = ethers.utils.arrayify(sig);
-    const rSlice = byt.slice(0, 32);
-    const sSlice = byt.slice(32, 64);
-
-    // Add the signature fields to make this a signed transaction.
-    tx.v = byt[64];
-    tx.r = ethers.BigNumber.from(rSlice).toString();
-    tx.s = ethers.BigNumber.from(sSlice).toString();
-    
-    // Marshal into JSON for the payload.
+    // Marshal into JSON for the payload. Go doesn't want big integers to be
+    // strings, so the signature values are added as numbers.
     var data = JSON.stringify(tx);
-
-    // Go doesn't want big integers to be strings. Removing quotes.
-    data = data.replace('r":"', 'r":');
-    data = data.replace('","s":"', ',"s":');
-    data = data.replace('"}', '}');
+    data = data.slice(0, -1) + ',"v":' + sig.v + ',"r":' + sig.r.toString() + ',"s":' + sig.s.toString() + '}';
 
     closeModal();
 
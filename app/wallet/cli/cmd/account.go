@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var accountCmd = &cobra.Command{
+	Use:   "account",
+	Short: "Print the account address for the key",
+	Run:   accountRun,
+}
+
+func init() {
+	rootCmd.AddCommand(accountCmd)
+}
+
+func accountRun(cmd *cobra.Command, args []string) {
+	accountID, err := loadAccountID()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(accountID)
+}
@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+)
+
+var balanceCmd = &cobra.Command{
+	Use:   "balance",
+	Short: "Print the balance of the account",
+	Run:   balanceRun,
+}
+
+func init() {
+	rootCmd.AddCommand(balanceCmd)
+}
+
+func balanceRun(cmd *cobra.Command, args []string) {
+	accountID, err := loadAccountID()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	account, err := queryAccount(accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("account: %s\nbalance: %d\nnonce:   %d\n", account.Account, account.Balance, account.Nonce)
+}
@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// client is used for all the calls to the node.
+var client = http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// The JSON shapes returned by the node's public API.
+type (
+	nodeAccount struct {
+		Account database.AccountID `json:"account"`
+		Balance uint64             `json:"balance"`
+		Nonce   uint64             `json:"nonce"`
+	}
+
+	nodeAccounts struct {
+		LatestBlock string        `json:"latest_block"`
+		Uncommitted int           `json:"uncommitted"`
+		Accounts    []nodeAccount `json:"accounts"`
+	}
+
+	nodeTx struct {
+		Hash   string             `json:"hash"`
+		Nonce  uint64             `json:"nonce"`
+		FromID database.AccountID `json:"from"`
+		ToID   database.AccountID `json:"to"`
+		Value  uint64             `json:"value"`
+		Tip    uint64             `json:"tip"`
+	}
+
+	nodeBlock struct {
+		Number       uint64   `json:"number"`
+		Transactions []nodeTx `json:"txs"`
+	}
+)
+
+// =============================================================================
+
+// loadPrivateKey loads the key for the account. The keystore is used when
+// it holds a key for the account, otherwise the plaintext key file is used.
+func loadPrivateKey() (*ecdsa.PrivateKey, error) {
+	path := getKeystorePath()
+	if _, err := os.Stat(path); err != nil {
+		return crypto.LoadECDSA(getPrivateKeyPath())
+	}
+
+	password, err := readPassword(keystorePassFile, "Password: ")
+	if err != nil {
+		return nil, err
+	}
+
+	return keystore.Load(path, password)
+}
+
+// loadAccountID returns the account of the key without decrypting the key
+// when it's stored in the keystore.
+func loadAccountID() (database.AccountID, error) {
+	path := getKeystorePath()
+	if _, err := os.Stat(path); err == nil {
+		address, err := keystore.Address(path)
+		if err != nil {
+			return "", err
+		}
+		return database.ToAccountID(address)
+	}
+
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		return "", err
+	}
+
+	return database.PublicKeyToAccountID(privateKey.PublicKey), nil
+}
+
+// =============================================================================
+
+// queryAccount returns the account information known to the node.
+func queryAccount(accountID database.AccountID) (nodeAccount, error) {
+	var resp nodeAccounts
+	if err := send(http.MethodGet, "/v1/accounts/list/"+string(accountID), nil, &resp); err != nil {
+		return nodeAccount{}, err
+	}
+
+	if len(resp.Accounts) != 1 {
+		return nodeAccount{}, fmt.Errorf("expected 1 account, got %d", len(resp.Accounts))
+	}
+
+	return resp.Accounts[0], nil
+}
+
+// queryMempool returns the uncommitted transactions sent or received by
+// the account.
+func queryMempool(accountID database.AccountID) ([]nodeTx, error) {
+	var trans []nodeTx
+	if err := send(http.MethodGet, "/v1/tx/uncommitted/list/"+string(accountID), nil, &trans); err != nil {
+		return nil, err
+	}
+
+	return trans, nil
+}
+
+// queryBlocks returns the blocks holding a transaction sent or received by
+// the account.
+func queryBlocks(accountID database.AccountID) ([]nodeBlock, error) {
+	var blocks []nodeBlock
+	if err := send(http.MethodGet, "/v1/blocks/list/"+string(accountID), nil, &blocks); err != nil {
+		return nil, err
+	}
+
+	return blocks, nil
+}
+
+// send performs the HTTP call against the node and decodes the response
+// into the specified value. A 204 leaves the value untouched.
+func send(method string, path string, dataSend any, dataRecv any) error {
+	var body io.Reader
+	if dataSend != nil {
+		data, err := json.Marshal(dataSend)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+
+	url := strings.TrimSuffix(nodeURL, "/") + path
+	req, err := http.NewRequest(method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNoContent:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		var er struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&er); err != nil || er.Error == "" {
+			return fmt.Errorf("%s %s: %s", method, url, resp.Status)
+		}
+		return errors.New(er.Error)
+	}
+
+	if dataRecv == nil {
+		return nil
+	}
+
+	return json.NewDecoder(resp.Body).Decode(dataRecv)
+}
@@ -3,10 +3,13 @@ package cmd
 import (
 	"log"
 
+	"github.com/ardanlabs/blockchain/foundation/keystore"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/spf13/cobra"
 )
 
+var generateEncrypt bool
+
 var generateCmd = &cobra.Command{
 	Use:   "generate",
 	Short: "Generate new key pair",
@@ -15,6 +18,7 @@ var generateCmd = &cobra.Command{
 
 func init() {
 	rootCmd.AddCommand(generateCmd)
+	generateCmd.Flags().BoolVarP(&generateEncrypt, "encrypt", "e", false, "Write the key to the keystore encrypted with a password.")
 }
 
 func generateRun(cmd *cobra.Command, args []string) {
@@ -23,6 +27,18 @@ func generateRun(cmd *cobra.Command, args []string) {
 		log.Fatal(err)
 	}
 
+	if generateEncrypt {
+		password, err := readNewPassword(keystorePassFile)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if err := keystore.Save(getKeystorePath(), privateKey, password, keystore.StandardScryptN); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	if err := crypto.SaveECDSA(getPrivateKeyPath(), privateKey); err != nil {
 		log.Fatal(err)
 	}
@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+)
+
+var (
+	genesisChainID       int16
+	genesisTransPerBlock int16
+	genesisDifficulty    int16
+	genesisReward        int64
+	genesisGasPrice      int64
+	genesisBalance       uint64
+	genesisOut           string
+	genesisSigners       []string
+)
+
+var genesisCmd = &cobra.Command{
+	Use:   "genesis",
+	Short: "Generate a genesis file funding every account",
+	Run:   genesisRun,
+}
+
+func init() {
+	rootCmd.AddCommand(genesisCmd)
+	genesisCmd.Flags().Int16Var(&genesisChainID, "chain-id", 1, "The chain id of the blockchain.")
+	genesisCmd.Flags().Int16Var(&genesisTransPerBlock, "trans-per-block", 10, "The max number of transactions per block.")
+	genesisCmd.Flags().Int16Var(&genesisDifficulty, "difficulty", 6, "The number of leading zeros required to mine a block.")
+	genesisCmd.Flags().Int64Var(&genesisReward, "reward", 700, "The reward for mining a block.")
+	genesisCmd.Flags().Int64Var(&genesisGasPrice, "gas-price", 15, "The price of one unit of gas.")
+	genesisCmd.Flags().Uint64Var(&genesisBalance, "balance", 1_000_000, "The starting balance for every account.")
+	genesisCmd.Flags().StringVarP(&genesisOut, "out", "o", "zblock/genesis.json", "Path to write the genesis file.")
+	genesisCmd.Flags().StringSliceVar(&genesisSigners, "signers", nil, "The accounts, in turn order, allowed to produce blocks under proof of authority. Leave empty for proof of work.")
+}
+
+func genesisRun(cmd *cobra.Command, args []string) {
+	files, err := filepath.Glob(filepath.Join(accountPath, "*"+keyExtenstion))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	balances := make(map[string]uint64)
+	for _, file := range files {
+		privateKey, err := crypto.LoadECDSA(file)
+		if err != nil {
+			log.Fatalf("%s: %s", file, err)
+		}
+
+		accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+		balances[string(accountID)] = genesisBalance
+
+		log.Printf("funding %s: %s", strings.TrimSuffix(filepath.Base(file), keyExtenstion), accountID)
+	}
+
+	signers := make([]string, 0, len(genesisSigners))
+	for _, name := range genesisSigners {
+		privateKey, err := crypto.LoadECDSA(filepath.Join(accountPath, name+keyExtenstion))
+		if err != nil {
+			log.Fatalf("signer %s: %s", name, err)
+		}
+
+		accountID := database.PublicKeyToAccountID(privateKey.PublicKey)
+		signers = append(signers, string(accountID))
+
+		log.Printf("signer %s: %s", name, accountID)
+	}
+
+	gen := genesis.Genesis{
+		Date:          time.Now().UTC().Truncate(time.Second),
+		ChainID:       genesisChainID,
+		TransPerBlock: genesisTransPerBlock,
+		Difficulty:    genesisDifficulty,
+		MiningReward:  genesisReward,
+		GasPrice:      genesisGasPrice,
+		Balances:      balances,
+		Signers:       signers,
+	}
+
+	if err := gen.Validate(); err != nil {
+		log.Fatal(err)
+	}
+
+	data, err := json.MarshalIndent(gen, "", "  ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := os.WriteFile(genesisOut, append(data, '\n'), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+var (
+	keystoreNewPassFile string
+	keystoreLight       bool
+	keystoreOut         string
+)
+
+const (
+	keystoreExtension = ".json"
+)
+
+var keystoreCmd = &cobra.Command{
+	Use:   "keystore",
+	Short: "Manage the password protected keys",
+}
+
+var keystoreImportCmd = &cobra.Command{
+	Use:   "import",
+	Short: "Encrypt the plaintext key for the account into the keystore",
+	Run:   keystoreImportRun,
+}
+
+var keystoreExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Decrypt the key for the account from the keystore into a plaintext key file",
+	Run:   keystoreExportRun,
+}
+
+var keystoreChangePasswordCmd = &cobra.Command{
+	Use:   "change-password",
+	Short: "Change the password protecting the key for the account",
+	Run:   keystoreChangePasswordRun,
+}
+
+var keystoreListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the keys in the keystore",
+	Run:   keystoreListRun,
+}
+
+func init() {
+	rootCmd.AddCommand(keystoreCmd)
+	keystoreCmd.AddCommand(keystoreImportCmd, keystoreExportCmd, keystoreChangePasswordCmd, keystoreListCmd)
+
+	keystoreImportCmd.Flags().BoolVar(&keystoreLight, "light", false, "Use a light scrypt cost. Only for throw away keys.")
+	keystoreChangePasswordCmd.Flags().BoolVar(&keystoreLight, "light", false, "Use a light scrypt cost. Only for throw away keys.")
+	keystoreChangePasswordCmd.Flags().StringVar(&keystoreNewPassFile, "new-password-file", "", "File holding the new password. When empty the password is prompted for.")
+	keystoreExportCmd.Flags().StringVarP(&keystoreOut, "out", "o", "", "Path to write the plaintext key. Defaults to the account's key file.")
+}
+
+func keystoreImportRun(cmd *cobra.Command, args []string) {
+	privateKey, err := crypto.LoadECDSA(getPrivateKeyPath())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	path := getKeystorePath()
+	if _, err := os.Stat(path); err == nil {
+		log.Fatalf("%s already exists", path)
+	}
+
+	password, err := readNewPassword(keystorePassFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := keystore.Save(path, privateKey, password, scryptN()); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("imported %s into %s\n", crypto.PubkeyToAddress(privateKey.PublicKey), path)
+}
+
+func keystoreExportRun(cmd *cobra.Command, args []string) {
+	password, err := readPassword(keystorePassFile, "Password: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	privateKey, err := keystore.Load(getKeystorePath(), password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	out := keystoreOut
+	if out == "" {
+		out = getPrivateKeyPath()
+	}
+
+	if _, err := os.Stat(out); err == nil {
+		log.Fatalf("%s already exists", out)
+	}
+
+	if err := crypto.SaveECDSA(out, privateKey); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("exported %s to %s\n", crypto.PubkeyToAddress(privateKey.PublicKey), out)
+}
+
+func keystoreChangePasswordRun(cmd *cobra.Command, args []string) {
+	path := getKeystorePath()
+
+	password, err := readPassword(keystorePassFile, "Current password: ")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	privateKey, err := keystore.Load(path, password)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	newPassword, err := readNewPassword(keystoreNewPassFile)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := keystore.Save(path, privateKey, newPassword, scryptN()); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("changed password for %s\n", crypto.PubkeyToAddress(privateKey.PublicKey))
+}
+
+func keystoreListRun(cmd *cobra.Command, args []string) {
+	files, err := filepath.Glob(filepath.Join(keystorePath, "*"+keystoreExtension))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, file := range files {
+		address, err := keystore.Address(file)
+		if err != nil {
+			log.Printf("%s: %s", file, err)
+			continue
+		}
+
+		fmt.Printf("%-10s %s\n", strings.TrimSuffix(filepath.Base(file), keystoreExtension), address)
+	}
+}
+
+// =============================================================================
+
+func getKeystorePath() string {
+	name := strings.TrimSuffix(accountName, keyExtenstion)
+	return filepath.Join(keystorePath, name+keystoreExtension)
+}
+
+func scryptN() int {
+	if keystoreLight {
+		return keystore.LightScryptN
+	}
+
+	return keystore.StandardScryptN
+}
+
+// readPassword reads the password from the specified file or prompts for
+// it on the terminal when no file is provided.
+func readPassword(file string, prompt string) (string, error) {
+	if file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	fmt.Fprint(os.Stderr, prompt)
+	password, err := term.ReadPassword(int(os.Stdin.Fd()))
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+
+	return string(password), nil
+}
+
+// readNewPassword reads a new password. When prompting, the password has to
+// be entered twice to protect against typos.
+func readNewPassword(file string) (string, error) {
+	password, err := readPassword(file, "New password: ")
+	if err != nil {
+		return "", err
+	}
+
+	if password == "" {
+		return "", errors.New("password can't be empty")
+	}
+
+	if file == "" {
+		confirm, err := readPassword("", "Repeat password: ")
+		if err != nil {
+			return "", err
+		}
+		if confirm != password {
+			return "", errors.New("passwords don't match")
+		}
+	}
+
+	return password, nil
+}
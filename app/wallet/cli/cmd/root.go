@@ -10,8 +10,11 @@ import (
 )
 
 var (
-	accountName string
-	accountPath string
+	accountName      string
+	accountPath      string
+	keystorePath     string
+	keystorePassFile string
+	nodeURL          string
 )
 
 const (
@@ -22,6 +25,9 @@ func init() {
 	rootCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	rootCmd.PersistentFlags().StringVarP(&accountName, "account", "a", "private.ecdsa", "The account to use.")
 	rootCmd.PersistentFlags().StringVarP(&accountPath, "account-path", "p", "zblock/accounts/", "Path to the directory with private keys.")
+	rootCmd.PersistentFlags().StringVarP(&keystorePath, "keystore-path", "k", "zblock/keystore/", "Path to the directory with the encrypted keys.")
+	rootCmd.PersistentFlags().StringVarP(&nodeURL, "url", "u", "http://localhost:8080", "URL of the node's public API.")
+	rootCmd.PersistentFlags().StringVar(&keystorePassFile, "password-file", "", "File holding the keystore password. When empty the password is prompted for.")
 }
 
 var rootCmd = &cobra.Command{
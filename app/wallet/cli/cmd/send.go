@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/spf13/cobra"
+)
+
+var (
+	sendTo    string
+	sendValue uint64
+	sendTip   uint64
+	sendData  string
+)
+
+var sendCmd = &cobra.Command{
+	Use:   "send",
+	Short: "Sign a transaction and submit it to the node",
+	Run:   sendRun,
+}
+
+func init() {
+	rootCmd.AddCommand(sendCmd)
+	sendCmd.Flags().StringVar(&sendTo, "to", "", "The account receiving the value.")
+	sendCmd.Flags().Uint64Var(&sendValue, "value", 0, "The value to send.")
+	sendCmd.Flags().Uint64Var(&sendTip, "tip", 0, "The tip offered to the miner.")
+	sendCmd.Flags().StringVar(&sendData, "data", "", "Data to attach to the transaction.")
+	sendCmd.MarkFlagRequired("to")
+}
+
+// walletTx is the signed transaction in the JSON shape the node accepts.
+type walletTx struct {
+	ChainID     uint16             `json:"chain_id"`
+	Nonce       uint64             `json:"nonce"`
+	FromAccount database.AccountID `json:"from"`
+	To          database.AccountID `json:"to"`
+	Value       uint64             `json:"value"`
+	Tip         uint64             `json:"tip"`
+	Data        []byte             `json:"data"`
+	V           *big.Int           `json:"v"`
+	R           *big.Int           `json:"r"`
+	S           *big.Int           `json:"s"`
+}
+
+func sendRun(cmd *cobra.Command, args []string) {
+	toID, err := database.ToAccountID(sendTo)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	privateKey, err := loadPrivateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	fromID := database.PublicKeyToAccountID(privateKey.PublicKey)
+
+	var gen genesis.Genesis
+	if err := send(http.MethodGet, "/v1/genesis/list", nil, &gen); err != nil {
+		log.Fatal(err)
+	}
+
+	nonce, err := nextNonce(fromID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var data []byte
+	if sendData != "" {
+		data = []byte(sendData)
+	}
+
+	tx, err := database.NewTx(uint16(gen.ChainID), nonce, fromID, toID, sendValue, sendTip, data)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	signedTx, err := tx.Sign(privateKey)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	wtx := walletTx{
+		ChainID:     signedTx.ChainID,
+		Nonce:       signedTx.Nonce,
+		FromAccount: signedTx.FromID,
+		To:          signedTx.ToID,
+		Value:       signedTx.Value,
+		Tip:         signedTx.Tip,
+		Data:        signedTx.Data,
+		V:           signedTx.V,
+		R:           signedTx.R,
+		S:           signedTx.S,
+	}
+
+	if err := send(http.MethodPost, "/v1/tx/submit", wtx, nil); err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Println(signedTx.TxHash())
+}
+
+// nextNonce returns the nonce for the next transaction of the account. The
+// transactions still waiting in the mempool have to be accounted for, else
+// the new transaction would replace the last one waiting.
+func nextNonce(accountID database.AccountID) (uint64, error) {
+	account, err := queryAccount(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	trans, err := queryMempool(accountID)
+	if err != nil {
+		return 0, err
+	}
+
+	nonce := account.Nonce
+	for _, tx := range trans {
+		if tx.FromID == accountID && tx.Nonce > nonce {
+			nonce = tx.Nonce
+		}
+	}
+
+	return nonce + 1, nil
+}
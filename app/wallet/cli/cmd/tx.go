@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var txCmd = &cobra.Command{
+	Use:   "tx",
+	Short: "Inspect transactions",
+}
+
+var txStatusCmd = &cobra.Command{
+	Use:   "status <hash>",
+	Short: "Print the status of a transaction sent or received by the account",
+	Args:  cobra.ExactArgs(1),
+	Run:   txStatusRun,
+}
+
+func init() {
+	rootCmd.AddCommand(txCmd)
+	txCmd.AddCommand(txStatusCmd)
+}
+
+func txStatusRun(cmd *cobra.Command, args []string) {
+	hash := strings.ToLower(args[0])
+
+	accountID, err := loadAccountID()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	trans, err := queryMempool(accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, tx := range trans {
+		if tx.Hash == hash {
+			fmt.Printf("pending: nonce %d waiting in the mempool\n", tx.Nonce)
+			return
+		}
+	}
+
+	blocks, err := queryBlocks(accountID)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	for _, block := range blocks {
+		for _, tx := range block.Transactions {
+			if tx.Hash == hash {
+				fmt.Printf("committed: nonce %d in block %d\n", tx.Nonce, block.Number)
+				return
+			}
+		}
+	}
+
+	log.Fatalf("transaction %s not found for account %s", args[0], accountID)
+}
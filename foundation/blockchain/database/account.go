@@ -1,6 +1,12 @@
 package database
 
-import "errors"
+import (
+	"crypto/ecdsa"
+	"errors"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+)
 
 // Account represents information stored in the database for an individual account.
 type Account struct {
@@ -16,18 +22,53 @@ func newAccount(accountID AccountID, balance uint64) Account {
 	}
 }
 
+// =============================================================================
+
+// byAccount provides sorting support by the account id value.
+type byAccount []Account
+
+// Len returns the number of accounts in the list.
+func (ba byAccount) Len() int {
+	return len(ba)
+}
+
+// Less helps to sort the list by account id in ascending order to keep the
+// accounts in the right order of processing.
+func (ba byAccount) Less(i, j int) bool {
+	return ba[i].AccountID < ba[j].AccountID
+}
+
+// Swap moves accounts in the order of the account id value.
+func (ba byAccount) Swap(i, j int) {
+	ba[i], ba[j] = ba[j], ba[i]
+}
+
+// =============================================================================
+
 // AccountID represents the unique identifier for an account.
 type AccountID string
 
 // ToAccountID converts a hex-encoded string to an account and validates the
-// hex-encoded string is formatted correctly.
+// hex-encoded string is formatted correctly. The account is returned in its
+// checksummed form.
 func ToAccountID(hex string) (AccountID, error) {
 	a := AccountID(hex)
 	if !a.IsAccountID() {
 		return "", errors.New("invalid account format")
 	}
 
-	return a, nil
+	return a.Normalize(), nil
+}
+
+// PublicKeyToAccountID converts the public key to an account value.
+func PublicKeyToAccountID(pk ecdsa.PublicKey) AccountID {
+	return AccountID(crypto.PubkeyToAddress(pk).String())
+}
+
+// Normalize returns the account in its checksummed form. Accounts are not
+// case sensitive, so any account used as a key must be normalized first.
+func (a AccountID) Normalize() AccountID {
+	return AccountID(common.HexToAddress(string(a)).Hex())
 }
 
 // IsAccountID verifies whether the underlying data represents a valid
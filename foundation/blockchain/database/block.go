@@ -0,0 +1,407 @@
+package database
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+)
+
+// maxHashDifficulty is the number of hex digits in a block hash. A difficulty
+// above it can never be solved and would claim unbounded chain work.
+const maxHashDifficulty = 64
+
+// maxFutureBlockTime is how far in the future a block's timestamp can be
+// compared to the local clock. Without a limit, a single block dated ahead
+// would make every block mined after it look older than its parent.
+const maxFutureBlockTime = 15 * time.Second
+
+// BlockHeader represents common information required for each block.
+type BlockHeader struct {
+	Number        uint64    `json:"number"`          // Ethereum: Block number in the chain.
+	PrevBlockHash string    `json:"prev_block_hash"` // Bitcoin: Hash of the previous block in the chain.
+	TimeStamp     uint64    `json:"timestamp"`       // Bitcoin: Time the block was mined.
+	BeneficiaryID AccountID `json:"beneficiary"`     // Ethereum: The account who is receiving fees and tips.
+	Difficulty    uint16    `json:"difficulty"`      // Ethereum: Number of 0's needed to solve the hash solution.
+	MiningReward  uint64    `json:"mining_reward"`   // Ethereum: The reward for mining this block.
+	StateRoot     string    `json:"state_root"`      // Ethereum: Represents a hash of the accounts and their balances.
+	TransRoot     string    `json:"trans_root"`      // Both: Represents the merkle tree root hash for the transactions in this block.
+	Nonce         uint64    `json:"nonce"`           // Both: Value identified to solve the hash solution.
+	R             *big.Int  `json:"r,omitempty"`     // PoA: Part of the signature of the account who produced the block.
+	S             *big.Int  `json:"s,omitempty"`     // PoA: Part of the signature of the account who produced the block.
+	V             *big.Int  `json:"v,omitempty"`     // PoA: Recovery identifier of the signature.
+}
+
+// Block represents a group of transactions batched together.
+type Block struct {
+	Header     BlockHeader
+	MerkleTree *merkle.Tree[BlockTx]
+}
+
+// POWArgs represents the set of arguments required to run POW.
+type POWArgs struct {
+	BeneficiaryID AccountID
+	Difficulty    uint16
+	MiningReward  uint64
+	PrevBlock     Block
+	StateRoot     string
+	Trans         []BlockTx
+	EvHandler     func(v string, args ...any)
+}
+
+// POW constructs a new Block and performs the work to find a nonce that
+// solves the cryptographic POW puzzle. The work can be cancelled through
+// the context.
+func POW(ctx context.Context, args POWArgs) (Block, error) {
+	if args.EvHandler == nil {
+		args.EvHandler = func(v string, args ...any) {}
+	}
+
+	if args.Difficulty > maxHashDifficulty {
+		return Block{}, fmt.Errorf("difficulty %d is greater than the max difficulty %d", args.Difficulty, maxHashDifficulty)
+	}
+
+	// When mining the first block, the previous block's hash will be zero.
+	prevBlockHash := signature.ZeroHash
+	if args.PrevBlock.Header.Number > 0 {
+		prevBlockHash = args.PrevBlock.Hash()
+	}
+
+	// Construct a merkle tree from the transaction for this block. The root
+	// of this tree will be part of the block to be mined.
+	tree, err := merkle.NewTree(args.Trans)
+	if err != nil {
+		return Block{}, err
+	}
+
+	// Construct the block to be mined.
+	nb := Block{
+		Header: BlockHeader{
+			Number:        args.PrevBlock.Header.Number + 1,
+			PrevBlockHash: prevBlockHash,
+			TimeStamp:     uint64(time.Now().UTC().UnixMilli()),
+			BeneficiaryID: args.BeneficiaryID,
+			Difficulty:    args.Difficulty,
+			MiningReward:  args.MiningReward,
+			StateRoot:     args.StateRoot,
+			TransRoot:     tree.RootHex(),
+			Nonce:         0, // Will be identified by the POW algorithm.
+		},
+		MerkleTree: tree,
+	}
+
+	// Peform the proof of work mining operation.
+	if err := nb.performPOW(ctx, args.EvHandler); err != nil {
+		return Block{}, err
+	}
+
+	return nb, nil
+}
+
+// POAArgs represents the set of arguments required to produce a block
+// under proof of authority.
+type POAArgs struct {
+	BeneficiaryID AccountID
+	MiningReward  uint64
+	PrevBlock     Block
+	StateRoot     string
+	Trans         []BlockTx
+	SignerKey     *ecdsa.PrivateKey
+	EvHandler     func(v string, args ...any)
+}
+
+// POA constructs a new Block and signs it with the key of the authorized
+// signer producing the block. There is no puzzle to solve so the block has
+// a difficulty of zero.
+func POA(args POAArgs) (Block, error) {
+	if args.EvHandler == nil {
+		args.EvHandler = func(v string, args ...any) {}
+	}
+
+	args.EvHandler("viewer: POA: MINING: started")
+	defer args.EvHandler("viewer: POA: MINING: completed")
+
+	// When producing the first block, the previous block's hash will be zero.
+	prevBlockHash := signature.ZeroHash
+	if args.PrevBlock.Header.Number > 0 {
+		prevBlockHash = args.PrevBlock.Hash()
+	}
+
+	// Construct a merkle tree from the transaction for this block. The root
+	// of this tree will be part of the block to be signed.
+	tree, err := merkle.NewTree(args.Trans)
+	if err != nil {
+		return Block{}, err
+	}
+
+	nb := Block{
+		Header: BlockHeader{
+			Number:        args.PrevBlock.Header.Number + 1,
+			PrevBlockHash: prevBlockHash,
+			TimeStamp:     uint64(time.Now().UTC().UnixMilli()),
+			BeneficiaryID: args.BeneficiaryID,
+			MiningReward:  args.MiningReward,
+			StateRoot:     args.StateRoot,
+			TransRoot:     tree.RootHex(),
+		},
+		MerkleTree: tree,
+	}
+
+	// Sign the block so peers can verify it was produced by the
+	// authorized signer.
+	v, r, s, err := signature.Sign(nb.Header.unsigned(), args.SignerKey)
+	if err != nil {
+		return Block{}, err
+	}
+	nb.Header.R = r
+	nb.Header.S = s
+	nb.Header.V = v
+
+	args.EvHandler("database: POA: MINING: SIGNED: blk[%d]: hash[%s]", nb.Header.Number, nb.Hash())
+
+	return nb, nil
+}
+
+// Signer recovers the account that signed the block under proof of
+// authority. An error is returned if the block is not signed.
+func (b Block) Signer() (AccountID, error) {
+	if b.Header.R == nil || b.Header.S == nil || b.Header.V == nil {
+		return "", errors.New("block is not signed")
+	}
+
+	if err := signature.VerifySignature(b.Header.V, b.Header.R, b.Header.S); err != nil {
+		return "", err
+	}
+
+	address, err := signature.FromAddress(b.Header.unsigned(), b.Header.V, b.Header.R, b.Header.S)
+	if err != nil {
+		return "", err
+	}
+
+	return AccountID(address), nil
+}
+
+// unsigned returns a copy of the header without the signature. This is
+// the value that is signed by the producer of the block.
+func (bh BlockHeader) unsigned() BlockHeader {
+	bh.R = nil
+	bh.S = nil
+	bh.V = nil
+
+	return bh
+}
+
+// performPOW does the work of mining to find a valid hash for a specified
+// block. Pointer semantics are being used since a nonce is being discovered.
+func (b *Block) performPOW(ctx context.Context, ev func(v string, args ...any)) error {
+	ev("database: PerformPOW: MINING: started")
+	defer ev("viewer: PerformPOW: MINING: completed")
+
+	// Log the transactions that are a part of this potential block.
+	for _, tx := range b.MerkleTree.Values() {
+		ev("database: PerformPOW: MINING: tx[%s]", tx)
+	}
+
+	// Choose a random starting point for the nonce. After this, the nonce
+	// will be incremented by 1 until a solution is found by us or another node.
+	nBig, err := rand.Int(rand.Reader, big.NewInt(math.MaxInt64))
+	if err != nil {
+		return err
+	}
+	b.Header.Nonce = nBig.Uint64()
+
+	ev("viewer: PerformPOW: MINING: running")
+
+	// Loop until we or another node finds a solution for the next block.
+	var attempts uint64
+	for {
+		attempts++
+		if attempts%1_000_000 == 0 {
+			ev("viewer: PerformPOW: MINING: running: attempts[%d]", attempts)
+		}
+
+		// Did we timeout trying to solve the problem.
+		if ctx.Err() != nil {
+			ev("database: PerformPOW: MINING: CANCELLED")
+			return ctx.Err()
+		}
+
+		// Hash the block and check if we have solved the puzzle.
+		hash := b.Hash()
+		if !isHashSolved(b.Header.Difficulty, hash) {
+			b.Header.Nonce++
+			continue
+		}
+
+		// Did we timeout trying to solve the problem.
+		if ctx.Err() != nil {
+			ev("database: PerformPOW: MINING: CANCELLED")
+			return ctx.Err()
+		}
+
+		ev("database: PerformPOW: MINING: SOLVED: prevBlk[%s]: newBlk[%s]", b.Header.PrevBlockHash, hash)
+		ev("database: PerformPOW: MINING: attempts[%d]", attempts)
+
+		return nil
+	}
+}
+
+// Hash returns the unique hash for the Block.
+func (b Block) Hash() string {
+	if b.Header.Number == 0 {
+		return signature.ZeroHash
+	}
+
+	// CORE NOTE: Hashing the block header and not the whole block so the blockchain
+	// can be cryptographically checked by only needing block headers and not full
+	// blocks with the transaction data. This will support the ability to have pruned
+	// nodes and light clients in the future.
+	// - A pruned node stores all the block headers, but only a small number of full
+	//   blocks (maybe the last 1000 blocks). This allows for full cryptographic
+	//   validation of blocks and transactions without all the extra storage.
+	// - A light client keeps block headers and just enough sufficient information
+	//   to follow the latest set of blocks being produced. The do not validate
+	//   blocks, but can prove a transaction is in a block.
+
+	return signature.Hash(b.Header)
+}
+
+// ValidateBlock takes a block and validates it to be included into
+// the blockchain.
+func (b Block) ValidateBlock(previousBlock Block, stateRoot string, evHandler func(v string, args ...any)) error {
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block difficulty is the same or greater than parent block difficulty", b.Header.Number)
+
+	if b.Header.Difficulty < previousBlock.Header.Difficulty {
+		return fmt.Errorf("block difficulty is less than parent block difficulty, parent %d, block %d", previousBlock.Header.Difficulty, b.Header.Difficulty)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block difficulty is not greater than the max difficulty", b.Header.Number)
+
+	if b.Header.Difficulty > maxHashDifficulty {
+		return fmt.Errorf("block difficulty is greater than the max difficulty, got %d, max %d", b.Header.Difficulty, maxHashDifficulty)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block header can be encoded", b.Header.Number)
+
+	if _, err := b.Header.EncodeCanonical(); err != nil {
+		return fmt.Errorf("block header can't be encoded: %w", err)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block hash has been solved", b.Header.Number)
+
+	// Only the genesis block has the zero hash. Any other block with it was
+	// forged, since the zero hash would solve every difficulty.
+	hash := b.Hash()
+	if b.Header.Number > 0 && hash == signature.ZeroHash {
+		return fmt.Errorf("%s invalid block hash", hash)
+	}
+	if !isHashSolved(b.Header.Difficulty, hash) {
+		return fmt.Errorf("%s invalid block hash", hash)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block number is the next number", b.Header.Number)
+
+	nextNumber := previousBlock.Header.Number + 1
+	switch {
+	case b.Header.Number < nextNumber:
+		return fmt.Errorf("%w: this block is not the next number, got %d, exp %d", ErrChainForked, b.Header.Number, nextNumber)
+	case b.Header.Number > nextNumber:
+		return fmt.Errorf("this block is not the next number, got %d, exp %d", b.Header.Number, nextNumber)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: parent hash does match parent block", b.Header.Number)
+
+	if b.Header.PrevBlockHash != previousBlock.Hash() {
+		return fmt.Errorf("%w: parent block hash doesn't match our known parent, got %s, exp %s", ErrChainForked, b.Header.PrevBlockHash, previousBlock.Hash())
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: block's timestamp is not too far in the future", b.Header.Number)
+
+	blockTime := time.UnixMilli(int64(b.Header.TimeStamp))
+	if limit := time.Now().Add(maxFutureBlockTime); blockTime.After(limit) {
+		return fmt.Errorf("block timestamp is in the future, block %v, limit %v", blockTime, limit)
+	}
+
+	if previousBlock.Header.TimeStamp > 0 {
+		evHandler("database: ValidateBlock: validate: blk[%d]: check: block's timestamp is greater than parent block's timestamp", b.Header.Number)
+
+		parentTime := time.UnixMilli(int64(previousBlock.Header.TimeStamp))
+		if blockTime.Before(parentTime) {
+			return fmt.Errorf("block timestamp is before parent block, parent %v, block %v", parentTime, blockTime)
+		}
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: state root hash does match current database", b.Header.Number)
+
+	if b.Header.StateRoot != stateRoot {
+		return fmt.Errorf("state of the accounts are wrong, current %s, expected %s", stateRoot, b.Header.StateRoot)
+	}
+
+	evHandler("database: ValidateBlock: validate: blk[%d]: check: merkle root does match transactions", b.Header.Number)
+
+	if b.Header.TransRoot != b.MerkleTree.RootHex() {
+		return fmt.Errorf("merkle root does not match transactions, got %s, exp %s", b.MerkleTree.RootHex(), b.Header.TransRoot)
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// BlockData represents what can be serialized to disk and over the network.
+type BlockData struct {
+	Hash   string      `json:"hash"`
+	Header BlockHeader `json:"block"`
+	Trans  []BlockTx   `json:"trans"`
+}
+
+// NewBlockData constructs block data from a block.
+func NewBlockData(block Block) BlockData {
+	blockData := BlockData{
+		Hash:   block.Hash(),
+		Header: block.Header,
+		Trans:  block.MerkleTree.Values(),
+	}
+
+	return blockData
+}
+
+// ToBlock converts a storage block into a database block. A block whose
+// header can't be canonically encoded is rejected since it has no hash.
+func ToBlock(blockData BlockData) (Block, error) {
+	if _, err := blockData.Header.EncodeCanonical(); err != nil {
+		return Block{}, fmt.Errorf("block header can't be encoded: %w", err)
+	}
+
+	tree, err := merkle.NewTree(blockData.Trans)
+	if err != nil {
+		return Block{}, err
+	}
+
+	block := Block{
+		Header:     blockData.Header,
+		MerkleTree: tree,
+	}
+
+	return block, nil
+}
+
+// =============================================================================
+
+// isHashSolved checks the hash to make sure it complies with
+// the POW rules. We need to match a difficulty number of 0's.
+func isHashSolved(difficulty uint16, hash string) bool {
+	if difficulty > maxHashDifficulty || len(hash) != 66 {
+		return false
+	}
+
+	return strings.HasPrefix(hash, "0x"+strings.Repeat("0", int(difficulty)))
+}
@@ -2,42 +2,119 @@
 package database
 
 import (
+	"context"
+	"crypto/ecdsa"
 	"errors"
+	"fmt"
 	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"math/bits"
+	"sort"
 	"sync"
 )
 
+// Set of errors returned when a transaction can't be applied to the
+// database. Use errors.Is to tell the rejections apart.
+var (
+	ErrUnknownAccount    = errors.New("account doesn't exist")
+	ErrInvalidNonce      = errors.New("transaction has an invalid nonce")
+	ErrInsufficientFunds = errors.New("account has insufficient funds")
+)
+
+// ErrInvalidTransaction is returned when a block contains a transaction that
+// wasn't signed by its sender for this chain or that charges gas the genesis
+// doesn't allow.
+var ErrInvalidTransaction = errors.New("block has an invalid transaction")
+
+// GasUnitsPerTx is the number of units of gas charged for every transaction.
+const GasUnitsPerTx = 1
+
+// ErrChainForked is returned when a block doesn't build on top of the
+// latest block, which means this node and the block producer don't
+// agree on the chain.
+var ErrChainForked = errors.New("blockchain forked")
+
 // Database manages the data related to the accounts who have transacted on the blockchain.
 type Database struct {
-	mu      sync.RWMutex
-	genesis genesis.Genesis
-	//latestBlock Block
-	accounts map[AccountID]Account
-	//storage     Storage
+	mu          sync.RWMutex
+	genesis     genesis.Genesis
+	latestBlock Block
+	accounts    map[AccountID]Account
+	evHandler   func(v string, args ...any)
+	storage     Storage
 }
 
-// New constructs a new Database value with the provided genesis block and event handler.
-func New(genesis genesis.Genesis, evHandler func(v string, args ...any)) (*Database, error) {
+// New constructs a new Database value with the provided genesis block, the
+// storage used to persist blocks and an event handler. Any blocks found in
+// storage are replayed on top of the genesis balances to rebuild the state
+// of the accounts.
+func New(genesis genesis.Genesis, storage Storage, evHandler func(v string, args ...any)) (*Database, error) {
+	if evHandler == nil {
+		evHandler = func(v string, args ...any) {}
+	}
+
 	db := Database{
-		genesis:  genesis,
-		accounts: make(map[AccountID]Account),
+		genesis:   genesis,
+		evHandler: evHandler,
+		storage:   storage,
 	}
-	for accountStr, balance := range genesis.Balances {
-		accountID, err := ToAccountID(accountStr)
-		if err != nil {
-			return nil, err
-		}
-		db.accounts[accountID] = newAccount(accountID, balance)
+
+	if err := db.resetAccounts(); err != nil {
+		return nil, err
+	}
+
+	// Read all the blocks from storage, validate each one against its parent
+	// and apply the transactions to rebuild the account state.
+	if err := db.replay(); err != nil {
+		return nil, err
 	}
+
 	return &db, nil
 }
 
+// Close closes the open blocks database.
+func (db *Database) Close() error {
+	return db.storage.Close()
+}
+
+// Reset re-initializes the database back to the genesis state.
+func (db *Database) Reset() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.storage.Reset(); err != nil {
+		return err
+	}
+
+	return db.resetAccounts()
+}
+
+// Rollback removes every block after the specified block number from
+// storage. The account state is rebuilt by replaying the remaining blocks
+// on top of the genesis balances. A block number of 0 rolls the database
+// back to the genesis state. The lock is held for the whole rollback so
+// readers never see a partially rebuilt state.
+func (db *Database) Rollback(num uint64) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if err := db.storage.Rollback(num); err != nil {
+		return err
+	}
+
+	if err := db.resetAccounts(); err != nil {
+		return err
+	}
+
+	return db.replay()
+}
+
 // Remove removes the account from the database.
 func (db *Database) Remove(accountID AccountID) {
 	db.mu.Lock()
 	defer db.mu.Unlock()
 
-	delete(db.accounts, accountID)
+	delete(db.accounts, accountID.Normalize())
 }
 
 // Query retrieves the account from the database.
@@ -45,9 +122,9 @@ func (db *Database) Query(accountID AccountID) (Account, error) {
 	db.mu.RLock()
 	defer db.mu.RUnlock()
 
-	account, ok := db.accounts[accountID]
+	account, ok := db.accounts[accountID.Normalize()]
 	if !ok {
-		return Account{}, errors.New("account doesn't exist")
+		return Account{}, ErrUnknownAccount
 	}
 	return account, nil
 }
@@ -63,3 +140,325 @@ func (db *Database) Copy() map[AccountID]Account {
 	}
 	return accounts
 }
+
+// HashState returns a hash based on the contents of the accounts and their
+// balances and nonces. The accounts are sorted by account id before hashing
+// so every node produces the same hash for the same state regardless of map
+// iteration order.
+func (db *Database) HashState() string {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.hashState()
+}
+
+// ApplyMiningReward gives the beneficiary account the mining reward defined
+// in the genesis file for mining the specified block.
+func (db *Database) ApplyMiningReward(block Block) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.applyMiningReward(block)
+}
+
+// ApplyTransaction performs the business logic for applying a transaction
+// to the database. The from account pays the value, tip and gas fee. The
+// value goes to the to account and the tip and gas fee go to the beneficiary
+// of the block. Nothing is changed if the transaction is rejected. Accounts
+// are keyed by their checksummed form, whatever case the transaction uses.
+func (db *Database) ApplyTransaction(block Block, tx BlockTx) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	return db.applyTransaction(block, tx)
+}
+
+// ValidateTransactions checks every transaction in the block before any of
+// them are applied. The producer of a block chooses its transactions and
+// the gas they are charged, so nothing in a block from a peer is trusted.
+func (db *Database) ValidateTransactions(block Block) error {
+	for _, tx := range block.MerkleTree.Values() {
+		if err := db.ValidateBlockTx(tx); err != nil {
+			return fmt.Errorf("blk[%d]: %w", block.Header.Number, err)
+		}
+	}
+
+	return nil
+}
+
+// ValidateBlockTx checks the transaction was signed by its sender for this
+// chain and charges the gas set by the genesis.
+func (db *Database) ValidateBlockTx(tx BlockTx) error {
+	if err := tx.Validate(uint16(db.genesis.ChainID)); err != nil {
+		return fmt.Errorf("%w: tx[%s]: %s", ErrInvalidTransaction, tx, err)
+	}
+
+	if tx.GasPrice != uint64(db.genesis.GasPrice) {
+		return fmt.Errorf("%w: tx[%s]: gas price is %d, exp %d", ErrInvalidTransaction, tx, tx.GasPrice, db.genesis.GasPrice)
+	}
+
+	if tx.GasUnit != GasUnitsPerTx {
+		return fmt.Errorf("%w: tx[%s]: gas units are %d, exp %d", ErrInvalidTransaction, tx, tx.GasUnit, GasUnitsPerTx)
+	}
+
+	return nil
+}
+
+// LatestBlock returns the latest block.
+func (db *Database) LatestBlock() Block {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.latestBlock
+}
+
+// UpdateLatestBlock provides safe access to update the latest block.
+func (db *Database) UpdateLatestBlock(block Block) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.latestBlock = block
+}
+
+// Write adds a new block to the chain.
+func (db *Database) Write(block Block) error {
+	return db.storage.Write(NewBlockData(block))
+}
+
+// GetBlock searches the blockchain on disk to locate and return the
+// contents of the specified block by number.
+func (db *Database) GetBlock(num uint64) (Block, error) {
+	blockData, err := db.storage.GetBlock(num)
+	if err != nil {
+		return Block{}, err
+	}
+
+	return ToBlock(blockData)
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (db *Database) ForEach() DatabaseIterator {
+	return DatabaseIterator{iterator: db.storage.ForEach()}
+}
+
+// POW mines a new block on top of the latest block using the difficulty and
+// mining reward defined in the genesis file. Progress is reported through
+// the event handler provided when the database was constructed.
+func (db *Database) POW(ctx context.Context, beneficiaryID AccountID, stateRoot string, trans []BlockTx) (Block, error) {
+	args := POWArgs{
+		BeneficiaryID: beneficiaryID,
+		Difficulty:    uint16(db.genesis.Difficulty),
+		MiningReward:  uint64(db.genesis.MiningReward),
+		PrevBlock:     db.LatestBlock(),
+		StateRoot:     stateRoot,
+		Trans:         trans,
+		EvHandler:     db.evHandler,
+	}
+
+	return POW(ctx, args)
+}
+
+// POA produces a new block on top of the latest block signed by the
+// specified signer using the mining reward defined in the genesis file.
+func (db *Database) POA(beneficiaryID AccountID, stateRoot string, trans []BlockTx, signerKey *ecdsa.PrivateKey) (Block, error) {
+	args := POAArgs{
+		BeneficiaryID: beneficiaryID,
+		MiningReward:  uint64(db.genesis.MiningReward),
+		PrevBlock:     db.LatestBlock(),
+		StateRoot:     stateRoot,
+		Trans:         trans,
+		SignerKey:     signerKey,
+		EvHandler:     db.evHandler,
+	}
+
+	return POA(args)
+}
+
+// =============================================================================
+
+// hashState performs the work of HashState. The caller must hold the lock.
+func (db *Database) hashState() string {
+	accounts := make([]Account, 0, len(db.accounts))
+	for _, account := range db.accounts {
+		accounts = append(accounts, account)
+	}
+
+	sort.Sort(byAccount(accounts))
+	return signature.Hash(accounts)
+}
+
+// applyMiningReward performs the work of ApplyMiningReward. The caller must
+// hold the lock.
+func (db *Database) applyMiningReward(block Block) {
+	beneficiaryID := block.Header.BeneficiaryID.Normalize()
+
+	account, exists := db.accounts[beneficiaryID]
+	if !exists {
+		account = newAccount(beneficiaryID, 0)
+	}
+
+	account.Balance += uint64(db.genesis.MiningReward)
+
+	db.accounts[beneficiaryID] = account
+}
+
+// applyTransaction performs the work of ApplyTransaction. The caller must
+// hold the lock.
+func (db *Database) applyTransaction(block Block, tx BlockTx) error {
+	fromID := tx.FromID.Normalize()
+	toID := tx.ToID.Normalize()
+	beneficiaryID := block.Header.BeneficiaryID.Normalize()
+
+	from, exists := db.accounts[fromID]
+	if !exists {
+		return fmt.Errorf("%w: from[%s]", ErrUnknownAccount, tx.FromID)
+	}
+
+	if tx.Nonce != from.Nonce+1 {
+		return fmt.Errorf("%w: got %d, exp %d", ErrInvalidNonce, tx.Nonce, from.Nonce+1)
+	}
+
+	gasFee, cost, err := txCost(tx, uint64(db.genesis.GasPrice))
+	if err != nil {
+		return fmt.Errorf("%w: account[%s]: %s", ErrInsufficientFunds, tx.FromID, err)
+	}
+	if from.Balance < cost {
+		return fmt.Errorf("%w: account[%s] balance[%d] cost[%d]", ErrInsufficientFunds, tx.FromID, from.Balance, cost)
+	}
+
+	from.Balance -= cost
+	from.Nonce = tx.Nonce
+	db.accounts[fromID] = from
+
+	to, exists := db.accounts[toID]
+	if !exists {
+		to = newAccount(toID, 0)
+	}
+	to.Balance += tx.Value
+	db.accounts[toID] = to
+
+	bnfc, exists := db.accounts[beneficiaryID]
+	if !exists {
+		bnfc = newAccount(beneficiaryID, 0)
+	}
+	bnfc.Balance += tx.Tip + gasFee
+	db.accounts[beneficiaryID] = bnfc
+
+	return nil
+}
+
+// txCost calculates the gas fee and the total cost of the transaction to
+// the from account. Any calculation that overflows is rejected, otherwise a
+// wrapped cost could pass the balance check.
+func txCost(tx BlockTx, gasPrice uint64) (gasFee uint64, cost uint64, err error) {
+	hi, gasFee := bits.Mul64(gasPrice, tx.GasUnit)
+	if hi != 0 {
+		return 0, 0, errors.New("gas fee overflows")
+	}
+
+	cost, carry := bits.Add64(tx.Value, tx.Tip, 0)
+	if carry != 0 {
+		return 0, 0, errors.New("cost overflows")
+	}
+
+	cost, carry = bits.Add64(cost, gasFee, 0)
+	if carry != 0 {
+		return 0, 0, errors.New("cost overflows")
+	}
+
+	return gasFee, cost, nil
+}
+
+// resetAccounts sets the accounts back to the genesis balances and clears
+// the latest block. The caller must hold the lock when the database is
+// in use.
+func (db *Database) resetAccounts() error {
+	accounts := make(map[AccountID]Account)
+	for accountStr, balance := range db.genesis.Balances {
+		accountID, err := ToAccountID(accountStr)
+		if err != nil {
+			return err
+		}
+		accounts[accountID] = newAccount(accountID, balance)
+	}
+
+	db.accounts = accounts
+	db.latestBlock = Block{}
+
+	return nil
+}
+
+// replay reads all the blocks from storage, validates each one against its
+// parent and applies the transactions and mining reward to the accounts.
+// The caller must hold the lock when the database is in use.
+func (db *Database) replay() error {
+	iter := db.ForEach()
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return err
+		}
+
+		if err := block.ValidateBlock(db.latestBlock, db.hashState(), db.evHandler); err != nil {
+			return err
+		}
+
+		if err := db.ValidateTransactions(block); err != nil {
+			return err
+		}
+
+		for _, tx := range block.MerkleTree.Values() {
+			if err := db.applyTransaction(block, tx); err != nil {
+				db.evHandler("database: replay: blk[%d]: tx[%s]: ERROR: %s", block.Header.Number, tx, err)
+			}
+		}
+
+		db.applyMiningReward(block)
+		db.latestBlock = block
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Storage interface represents the behavior required to be implemented by any
+// package providing support for reading and writing the blockchain.
+type Storage interface {
+	Write(blockData BlockData) error
+	GetBlock(num uint64) (BlockData, error)
+	ForEach() Iterator
+	Close() error
+	Reset() error
+	Rollback(num uint64) error
+}
+
+// Iterator interface represents the behavior required to be implemented by any
+// package providing support to iterate over the blocks.
+type Iterator interface {
+	Next() (BlockData, error)
+	Done() bool
+}
+
+// =============================================================================
+
+// DatabaseIterator provides support for iterating over the blocks in the
+// blockchain database using the configured storage option.
+type DatabaseIterator struct {
+	iterator Iterator
+}
+
+// Next retrieves the next block from disk.
+func (di *DatabaseIterator) Next() (Block, error) {
+	blockData, err := di.iterator.Next()
+	if err != nil {
+		return Block{}, err
+	}
+
+	return ToBlock(blockData)
+}
+
+// Done returns the end of chain value.
+func (di *DatabaseIterator) Done() bool {
+	return di.iterator.Done()
+}
@@ -0,0 +1,163 @@
+package database_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+func TestApplyTransactionCost(t *testing.T) {
+	pk := mustKey(t, fromKey)
+	fromID := database.PublicKeyToAccountID(pk.PublicKey)
+
+	tt := []struct {
+		name    string
+		value   uint64
+		tip     uint64
+		gasUnit uint64
+		err     error
+	}{
+		{name: "exact balance", value: balance - 10 - gasPrice, tip: 10, gasUnit: 1},
+		{name: "insufficient funds", value: balance, tip: 10, gasUnit: 1, err: database.ErrInsufficientFunds},
+		{name: "value and tip overflow", value: math.MaxUint64, tip: 2, gasUnit: 1, err: database.ErrInsufficientFunds},
+		{name: "tip and gas fee overflow", value: 1, tip: math.MaxUint64 - gasPrice + 1, gasUnit: 1, err: database.ErrInsufficientFunds},
+		{name: "gas fee overflow", value: 1, gasUnit: math.MaxUint64/gasPrice + 1, err: database.ErrInsufficientFunds},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			gen := genesis.Genesis{
+				ChainID:  int16(chainID),
+				GasPrice: int64(gasPrice),
+				Balances: map[string]uint64{string(fromID): balance},
+			}
+
+			db, err := database.New(gen, memory.New(), nil)
+			if err != nil {
+				t.Fatalf("Should be able to construct the database: %s", err)
+			}
+
+			tx, err := database.NewTx(chainID, 1, fromID, toID, tst.value, tst.tip, nil)
+			if err != nil {
+				t.Fatalf("Should be able to construct the transaction: %s", err)
+			}
+
+			block := database.Block{Header: database.BlockHeader{BeneficiaryID: minerID}}
+			err = db.ApplyTransaction(block, database.NewBlockTx(sign(t, pk, tx), gasPrice, tst.gasUnit))
+
+			switch {
+			case tst.err == nil && err != nil:
+				t.Fatalf("Should be able to apply the transaction: %s", err)
+			case tst.err != nil && !errors.Is(err, tst.err):
+				t.Fatalf("Should get the expected error, got %v, exp %v", err, tst.err)
+			}
+
+			// A rejected transaction must leave the from account untouched.
+			if tst.err != nil {
+				from, err := db.Query(fromID)
+				if err != nil {
+					t.Fatalf("Should be able to query the from account: %s", err)
+				}
+				if from.Balance != balance || from.Nonce != 0 {
+					t.Fatalf("Should not change the from account, got balance %d nonce %d", from.Balance, from.Nonce)
+				}
+			}
+		})
+	}
+}
+
+func TestNewCorruptBlock(t *testing.T) {
+	pk := mustKey(t, fromKey)
+	fromID := database.PublicKeyToAccountID(pk.PublicKey)
+
+	gen := genesis.Genesis{
+		ChainID:  int16(chainID),
+		GasPrice: int64(gasPrice),
+		Balances: map[string]uint64{string(fromID): balance},
+	}
+
+	// Mine the first block on top of the genesis state.
+	db, err := database.New(gen, memory.New(), nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	tx, err := database.NewTx(chainID, 1, fromID, toID, 100, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	block, err := database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: minerID,
+		Difficulty:    1,
+		PrevBlock:     database.Block{},
+		StateRoot:     db.HashState(),
+		Trans:         []database.BlockTx{database.NewBlockTx(sign(t, pk, tx), gasPrice, database.GasUnitsPerTx)},
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	tt := []struct {
+		name    string
+		corrupt func(t *testing.T) []byte
+	}{
+		{
+			name:    "unreadable block",
+			corrupt: func(t *testing.T) []byte { return []byte("{ not a block") },
+		},
+		{
+			name: "tampered transaction",
+			corrupt: func(t *testing.T) []byte {
+				blockData := database.NewBlockData(block)
+				blockData.Trans[0].Value = 900
+				return mustMarshal(t, blockData)
+			},
+		},
+		{
+			name: "tampered state root",
+			corrupt: func(t *testing.T) []byte {
+				blockData := database.NewBlockData(block)
+				blockData.Header.StateRoot = blockData.Header.TransRoot
+				return mustMarshal(t, blockData)
+			},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			storage := memory.New()
+			if err := storage.Write(database.NewBlockData(block)); err != nil {
+				t.Fatalf("Should be able to write the block: %s", err)
+			}
+
+			// The block is good until it's corrupted in storage.
+			if _, err := database.New(gen, storage, nil); err != nil {
+				t.Fatalf("Should be able to load the good block: %s", err)
+			}
+
+			storage.CorruptBlock(1, tst.corrupt(t))
+
+			if _, err := database.New(gen, storage, nil); err == nil {
+				t.Fatalf("Should not be able to load a corrupt block")
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+func mustMarshal(t *testing.T, v any) []byte {
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Should be able to marshal the value: %s", err)
+	}
+
+	return data
+}
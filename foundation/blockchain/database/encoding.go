@@ -0,0 +1,171 @@
+package database
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// CORE NOTE: Transactions and blocks are signed and hashed using a canonical
+// RLP encoding instead of their JSON form. The bytes produced by JSON depend on
+// Go field order and on how big integers and byte slices are encoded, so
+// clients written in other languages can't reproduce them. The RLP encoding
+// below is the specification: every value is encoded as a list, in the order
+// the fields are declared in the rlp types, with accounts as 20 byte
+// addresses and hashes as 32 byte values. JSON is still used by the API and
+// storage.
+
+// EncodingVersion is the version of the canonical encoding. It's the first
+// element of every encoded list so a future change to the encoding can never
+// produce the same bytes as this version.
+const EncodingVersion uint8 = 1
+
+// rlpTx is the canonical encoding of a Tx.
+type rlpTx struct {
+	Version uint8
+	ChainID uint16
+	Nonce   uint64
+	FromID  common.Address
+	ToID    common.Address
+	Value   uint64
+	Tip     uint64
+	Data    []byte
+}
+
+// rlpSignedTx is the canonical encoding of a SignedTx.
+type rlpSignedTx struct {
+	Version uint8
+	ChainID uint16
+	Nonce   uint64
+	FromID  common.Address
+	ToID    common.Address
+	Value   uint64
+	Tip     uint64
+	Data    []byte
+	R       *big.Int
+	S       *big.Int
+	V       *big.Int
+}
+
+// rlpBlockTx is the canonical encoding of a BlockTx.
+type rlpBlockTx struct {
+	Version   uint8
+	ChainID   uint16
+	Nonce     uint64
+	FromID    common.Address
+	ToID      common.Address
+	Value     uint64
+	Tip       uint64
+	Data      []byte
+	R         *big.Int
+	S         *big.Int
+	V         *big.Int
+	TimeStamp uint64
+	GasPrice  uint64
+	GasUnit   uint64
+}
+
+// rlpBlockHeader is the canonical encoding of a BlockHeader. The signature
+// values are encoded as empty when the header isn't signed.
+type rlpBlockHeader struct {
+	Version       uint8
+	Number        uint64
+	PrevBlockHash common.Hash
+	TimeStamp     uint64
+	BeneficiaryID common.Address
+	Difficulty    uint16
+	MiningReward  uint64
+	StateRoot     common.Hash
+	TransRoot     common.Hash
+	Nonce         uint64
+	R             *big.Int
+	S             *big.Int
+	V             *big.Int
+}
+
+// =============================================================================
+
+// EncodeCanonical implements the signature Encoder interface and returns the
+// canonical encoding of the transaction. This is what the sender signs.
+func (tx Tx) EncodeCanonical() ([]byte, error) {
+	return rlp.EncodeToBytes(rlpTx{
+		Version: EncodingVersion,
+		ChainID: tx.ChainID,
+		Nonce:   tx.Nonce,
+		FromID:  tx.FromID.address(),
+		ToID:    tx.ToID.address(),
+		Value:   tx.Value,
+		Tip:     tx.Tip,
+		Data:    tx.Data,
+	})
+}
+
+// EncodeCanonical implements the signature Encoder interface and returns the
+// canonical encoding of the signed transaction.
+func (tx SignedTx) EncodeCanonical() ([]byte, error) {
+	return rlp.EncodeToBytes(rlpSignedTx{
+		Version: EncodingVersion,
+		ChainID: tx.ChainID,
+		Nonce:   tx.Nonce,
+		FromID:  tx.FromID.address(),
+		ToID:    tx.ToID.address(),
+		Value:   tx.Value,
+		Tip:     tx.Tip,
+		Data:    tx.Data,
+		R:       tx.R,
+		S:       tx.S,
+		V:       tx.V,
+	})
+}
+
+// EncodeCanonical implements the signature Encoder interface and returns the
+// canonical encoding of the block transaction. This is what the merkle tree
+// of a block is built from.
+func (blockTx BlockTx) EncodeCanonical() ([]byte, error) {
+	return rlp.EncodeToBytes(rlpBlockTx{
+		Version:   EncodingVersion,
+		ChainID:   blockTx.ChainID,
+		Nonce:     blockTx.Nonce,
+		FromID:    blockTx.FromID.address(),
+		ToID:      blockTx.ToID.address(),
+		Value:     blockTx.Value,
+		Tip:       blockTx.Tip,
+		Data:      blockTx.Data,
+		R:         blockTx.R,
+		S:         blockTx.S,
+		V:         blockTx.V,
+		TimeStamp: blockTx.TimeStamp,
+		GasPrice:  blockTx.GasPrice,
+		GasUnit:   blockTx.GasUnit,
+	})
+}
+
+// EncodeCanonical implements the signature Encoder interface and returns the
+// canonical encoding of the block header. This is what the block hash is
+// calculated from.
+func (bh BlockHeader) EncodeCanonical() ([]byte, error) {
+	return rlp.EncodeToBytes(rlpBlockHeader{
+		Version:       EncodingVersion,
+		Number:        bh.Number,
+		PrevBlockHash: common.HexToHash(bh.PrevBlockHash),
+		TimeStamp:     bh.TimeStamp,
+		BeneficiaryID: bh.BeneficiaryID.address(),
+		Difficulty:    bh.Difficulty,
+		MiningReward:  bh.MiningReward,
+		StateRoot:     common.HexToHash(bh.StateRoot),
+		TransRoot:     common.HexToHash(bh.TransRoot),
+		Nonce:         bh.Nonce,
+		R:             bh.R,
+		S:             bh.S,
+		V:             bh.V,
+	})
+}
+
+// =============================================================================
+
+// address converts the account to its 20 byte form. The conversion doesn't
+// depend on the case of the hex characters.
+func (a AccountID) address() common.Address {
+	return common.HexToAddress(string(a))
+}
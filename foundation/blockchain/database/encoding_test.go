@@ -0,0 +1,132 @@
+package database_test
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The vectors below are the specification of the canonical encoding. Any
+// client signing or hashing transactions and blocks must reproduce them, so
+// they can only change together with EncodingVersion. The Chrome wallet in
+// app/wallet/chrome is checked against the same vectors.
+const (
+	vectorTxRLP = "0xf201010194f01813e4b85e178a83e29b8e7bf26bd830a25f3294dd6b972ffcc631a62cae1bb9d80b7ff429c8eba4640a826869"
+
+	vectorSignedTxRLP  = "0xf87501010194f01813e4b85e178a83e29b8e7bf26bd830a25f3294dd6b972ffcc631a62cae1bb9d80b7ff429c8eba4640a826869a05a88c0c3a44ed6ac50a43139889562df22e629618b01b7a64c82f03d1a795a05a034f662c13ff6cef0deee849f0d303d17b0bb86126edb40f41dee8d7d3e897cdb1b"
+	vectorSignedTxHash = "0x703da8f33ce52650ad42f4b1e1520d691dbcbf24a00168b70904d0d179eb8edf"
+
+	vectorBlockTxRLP  = "0xf87c01010194f01813e4b85e178a83e29b8e7bf26bd830a25f3294dd6b972ffcc631a62cae1bb9d80b7ff429c8eba4640a826869a05a88c0c3a44ed6ac50a43139889562df22e629618b01b7a64c82f03d1a795a05a034f662c13ff6cef0deee849f0d303d17b0bb86126edb40f41dee8d7d3e897cdb1b846553f1000f01"
+	vectorBlockTxHash = "0xbab2ea0bf118c16632cb0b788ed2c15a15c6fc5ce30ad426f17ca1d629c7a397"
+
+	vectorHeaderRLP  = "0xf8890101a0000000000000000000000000000000000000000000000000000000000000000086018bcfe5680094fef311483cc040e1a89fb9bb469eeb8a70935ef8068202bca00000000000000000000000000000000000000000000000000000000000000001a000000000000000000000000000000000000000000000000000000000000000022a808080"
+	vectorHeaderHash = "0x5a970a0bd226312246b02b46ee45ec1a66dcdab718e9045c001f9197b39b8ecf"
+
+	vectorSignedHeaderRLP  = "0xf8890101a0000000000000000000000000000000000000000000000000000000000000000086018bcfe5680094fef311483cc040e1a89fb9bb469eeb8a70935ef8068202bca00000000000000000000000000000000000000000000000000000000000000001a000000000000000000000000000000000000000000000000000000000000000022a01021b"
+	vectorSignedHeaderHash = "0x3795eac62b479aa40f8c12fdbe208dcabb7973b0d4e064595e07ae2510b2e391"
+
+	vectorR = "40949777983095667243935883145884113754362510531688291148283678138099064986117"
+	vectorS = "23955594085413232422417156922085814475294083488097639528775214660547982752987"
+	vectorV = "27"
+)
+
+func TestEncodingVectors(t *testing.T) {
+	signedTx := vectorSignedTx(t)
+	blockTx := database.BlockTx{SignedTx: signedTx, TimeStamp: 1700000000, GasPrice: 15, GasUnit: 1}
+
+	header := database.BlockHeader{
+		Number:        1,
+		PrevBlockHash: signature.ZeroHash,
+		TimeStamp:     1700000000000,
+		BeneficiaryID: minerID,
+		Difficulty:    6,
+		MiningReward:  700,
+		StateRoot:     fmt.Sprintf("0x%064x", 1),
+		TransRoot:     fmt.Sprintf("0x%064x", 2),
+		Nonce:         42,
+	}
+
+	signedHeader := header
+	signedHeader.R = big.NewInt(1)
+	signedHeader.S = big.NewInt(2)
+	signedHeader.V = big.NewInt(27)
+
+	tt := []struct {
+		name  string
+		value signature.Encoder
+		rlp   string
+		hash  string
+	}{
+		{name: "tx", value: signedTx.Tx, rlp: vectorTxRLP},
+		{name: "signed tx", value: signedTx, rlp: vectorSignedTxRLP, hash: vectorSignedTxHash},
+		{name: "block tx", value: blockTx, rlp: vectorBlockTxRLP, hash: vectorBlockTxHash},
+		{name: "header", value: header, rlp: vectorHeaderRLP, hash: vectorHeaderHash},
+		{name: "signed header", value: signedHeader, rlp: vectorSignedHeaderRLP, hash: vectorSignedHeaderHash},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			data, err := tst.value.EncodeCanonical()
+			if err != nil {
+				t.Fatalf("Should be able to encode the value: %s", err)
+			}
+
+			if got := hexutil.Encode(data); got != tst.rlp {
+				t.Fatalf("Should get the expected encoding\ngot: %s\nexp: %s", got, tst.rlp)
+			}
+
+			if tst.hash != "" {
+				if got := signature.Hash(tst.value); got != tst.hash {
+					t.Fatalf("Should get the expected hash, got %s, exp %s", got, tst.hash)
+				}
+			}
+
+			// The version must be the first element of the encoded list.
+			content, _, err := rlp.SplitList(data)
+			if err != nil {
+				t.Fatalf("Should be able to split the encoded list: %s", err)
+			}
+
+			version, _, err := rlp.SplitUint64(content)
+			if err != nil {
+				t.Fatalf("Should be able to read the encoding version: %s", err)
+			}
+
+			if version != uint64(database.EncodingVersion) {
+				t.Fatalf("Should start with the encoding version, got %d, exp %d", version, database.EncodingVersion)
+			}
+		})
+	}
+}
+
+func TestSignVector(t *testing.T) {
+	signedTx := vectorSignedTx(t)
+
+	if signedTx.R.String() != vectorR || signedTx.S.String() != vectorS || signedTx.V.String() != vectorV {
+		t.Fatalf("Should get the expected signature\ngot: r=%s s=%s v=%s\nexp: r=%s s=%s v=%s", signedTx.R, signedTx.S, signedTx.V, vectorR, vectorS, vectorV)
+	}
+
+	if err := signedTx.Validate(chainID); err != nil {
+		t.Fatalf("Should be able to validate the signed vector: %s", err)
+	}
+}
+
+// =============================================================================
+
+// vectorSignedTx signs the transaction the vectors are built from. Signing
+// is deterministic, so the signature is part of the vectors.
+func vectorSignedTx(t *testing.T) database.SignedTx {
+	pk := mustKey(t, fromKey)
+
+	tx, err := database.NewTx(chainID, 1, database.PublicKeyToAccountID(pk.PublicKey), toID, 100, 10, []byte("hi"))
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	return sign(t, pk, tx)
+}
@@ -77,18 +77,22 @@ func (tx SignedTx) Validate(chainID uint16) error {
 	if !tx.ToID.IsAccountID() {
 		return errors.New("invalid to account ID")
 	}
-	if tx.FromID == tx.ToID {
+	if tx.FromID.Normalize() == tx.ToID.Normalize() {
 		return errors.New("you could not transfer to yourself")
 	}
 	if err := signature.VerifySignature(tx.V, tx.R, tx.S); err != nil {
 		return err
 	}
-	address, err := signature.FromAddress(tx.Data, tx.V, tx.R, tx.S)
+	// Recover the address from the transaction that was signed, which is
+	// the Tx without the signature.
+	address, err := signature.FromAddress(tx.Tx, tx.V, tx.R, tx.S)
 	if err != nil {
 		return err
 	}
-	// Check if the from address is the same as the address derived from the signature.
-	if address != string(tx.FromID) {
+	// Check if the from address is the same as the address derived from the
+	// signature. Both are compared in their checksummed form so the case of
+	// the from account doesn't matter.
+	if AccountID(address).Normalize() != tx.FromID.Normalize() {
 		return errors.New("signature address is not match the from address")
 	}
 	return nil
@@ -99,6 +103,13 @@ func (tx SignedTx) SignatureString() string {
 	return signature.SigString(tx.V, tx.R, tx.S)
 }
 
+// TxHash returns the hash that identifies the signed transaction. Unlike the
+// hash of a BlockTx, it doesn't depend on the values the node adds, so the
+// wallet knows it as soon as the transaction is signed.
+func (tx SignedTx) TxHash() string {
+	return signature.Hash(tx)
+}
+
 // String returns the string representation of the transaction.
 func (tx Tx) String() string {
 	return fmt.Sprintf("%s:%d", tx.FromID, tx.Nonce)
@@ -124,16 +135,15 @@ func NewBlockTx(tx SignedTx, gasPrice, gasUnit uint64) BlockTx {
 }
 
 // Hash implement the merkle Hashable interface for providing a hash of the BlockTx.
-func (blockTx *BlockTx) Hash() ([]byte, error) {
+func (blockTx BlockTx) Hash() ([]byte, error) {
 	hash := signature.Hash(blockTx)
-	// remove the 0x prefix
-	return hexutil.Decode(hash[2:])
+	return hexutil.Decode(hash)
 }
 
 // Equal implements the merkle Hashable interface for providing the equality
 // check between two BlockTx.If the nonce and the signature are the same, then
 // the two BlockTx are considered equal.
-func (blockTx *BlockTx) Equal(other *BlockTx) bool {
+func (blockTx BlockTx) Equal(other BlockTx) bool {
 	sig1 := signature.ToSignatureBytes(blockTx.V, blockTx.R, blockTx.S)
 	sig2 := signature.ToSignatureBytes(other.V, other.R, other.S)
 
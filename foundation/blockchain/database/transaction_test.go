@@ -0,0 +1,248 @@
+package database_test
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	fromKey  = "9f332e3700d8fc2446eaf6d15034cf96e0c2745e40353deef032a5dbf1dfed93"
+	otherKey = "aed31b6b5a7e8b4b6e7fc4b4b5cfa6cc1d7f5d1a4f6c0b0c1e2d3f4a5b6c7d8e"
+	toID     = database.AccountID("0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4")
+	minerID  = database.AccountID("0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8")
+	chainID  = uint16(1)
+	balance  = uint64(1000)
+	gasPrice = uint64(15)
+)
+
+func TestSignedTxValidate(t *testing.T) {
+	pk := mustKey(t, fromKey)
+	other := mustKey(t, otherKey)
+	fromID := database.PublicKeyToAccountID(pk.PublicKey)
+
+	tt := []struct {
+		name    string
+		signed  func(t *testing.T) database.SignedTx
+		chainID uint16
+		err     string
+	}{
+		{
+			name:    "valid",
+			signed:  func(t *testing.T) database.SignedTx { return sign(t, pk, newTx(t, fromID, toID)) },
+			chainID: chainID,
+		},
+		{
+			name: "valid lower case from",
+			signed: func(t *testing.T) database.SignedTx {
+				return sign(t, pk, newTx(t, database.AccountID(strings.ToLower(string(fromID))), toID))
+			},
+			chainID: chainID,
+		},
+		{
+			name: "valid lower case to",
+			signed: func(t *testing.T) database.SignedTx {
+				return sign(t, pk, newTx(t, fromID, database.AccountID(strings.ToLower(string(toID)))))
+			},
+			chainID: chainID,
+		},
+		{
+			name: "forged value",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.Value = 1_000_000
+				return stx
+			},
+			chainID: chainID,
+			err:     "signature address is not match the from address",
+		},
+		{
+			name: "forged recipient",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.ToID = "0xbEE6ACE826eC3DE1B6349888B9151B92522F7F76"
+				return stx
+			},
+			chainID: chainID,
+			err:     "signature address is not match the from address",
+		},
+		{
+			name: "replayed with a new nonce",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.Nonce++
+				return stx
+			},
+			chainID: chainID,
+			err:     "signature address is not match the from address",
+		},
+		{
+			name: "replayed on another chain",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.ChainID = 2
+				return stx
+			},
+			chainID: 2,
+			err:     "signature address is not match the from address",
+		},
+		{
+			name:    "wrong chain",
+			signed:  func(t *testing.T) database.SignedTx { return sign(t, pk, newTx(t, fromID, toID)) },
+			chainID: 2,
+			err:     "invalid chainID",
+		},
+		{
+			name:    "mismatched sender",
+			signed:  func(t *testing.T) database.SignedTx { return sign(t, other, newTx(t, fromID, toID)) },
+			chainID: chainID,
+			err:     "signature address is not match the from address",
+		},
+		{
+			name: "high s value",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+
+				// Flip the signature to its malleable form, which recovers
+				// to the same public key.
+				stx.S = new(big.Int).Sub(crypto.S256().Params().N, stx.S)
+				stx.V = new(big.Int).Sub(big.NewInt(27+28), stx.V)
+				return stx
+			},
+			chainID: chainID,
+			err:     "invalid signature",
+		},
+		{
+			name: "invalid recovery id",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.V = big.NewInt(30)
+				return stx
+			},
+			chainID: chainID,
+			err:     "invalid recovery id",
+		},
+		{
+			name:    "send to self",
+			signed:  func(t *testing.T) database.SignedTx { return sign(t, pk, newTx(t, fromID, fromID)) },
+			chainID: chainID,
+			err:     "you could not transfer to yourself",
+		},
+		{
+			name: "send to self with lower case to",
+			signed: func(t *testing.T) database.SignedTx {
+				return sign(t, pk, newTx(t, fromID, database.AccountID(strings.ToLower(string(fromID)))))
+			},
+			chainID: chainID,
+			err:     "you could not transfer to yourself",
+		},
+		{
+			name: "invalid to account",
+			signed: func(t *testing.T) database.SignedTx {
+				stx := sign(t, pk, newTx(t, fromID, toID))
+				stx.ToID = "0x1234"
+				return stx
+			},
+			chainID: chainID,
+			err:     "invalid to account ID",
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			stx := tst.signed(t)
+			err := stx.Validate(tst.chainID)
+
+			switch {
+			case tst.err == "" && err != nil:
+				t.Fatalf("Should be able to validate the transaction: %s", err)
+			case tst.err != "" && err == nil:
+				t.Fatalf("Should not be able to validate the transaction, exp %q", tst.err)
+			case tst.err != "" && !strings.Contains(err.Error(), tst.err):
+				t.Fatalf("Should get the expected error, got %q, exp %q", err, tst.err)
+			}
+
+			// A transaction that validates must also apply to the accounts
+			// it names, whatever case the accounts are written in.
+			if tst.err == "" {
+				apply(t, fromID, stx)
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+func mustKey(t *testing.T, hexKey string) *ecdsa.PrivateKey {
+	pk, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		t.Fatalf("Should be able to load the private key: %s", err)
+	}
+
+	return pk
+}
+
+func newTx(t *testing.T, from database.AccountID, to database.AccountID) database.Tx {
+	tx, err := database.NewTx(chainID, 1, from, to, 100, 10, []byte("payment"))
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	return tx
+}
+
+// apply applies the transaction to a database where only the from account
+// is funded and checks the value moved between the checksummed accounts
+// without creating any other account.
+func apply(t *testing.T, fromID database.AccountID, stx database.SignedTx) {
+	gen := genesis.Genesis{
+		ChainID:  int16(chainID),
+		GasPrice: int64(gasPrice),
+		Balances: map[string]uint64{string(fromID): balance},
+	}
+
+	db, err := database.New(gen, memory.New(), nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	block := database.Block{Header: database.BlockHeader{BeneficiaryID: minerID}}
+	if err := db.ApplyTransaction(block, database.NewBlockTx(stx, gasPrice, 1)); err != nil {
+		t.Fatalf("Should be able to apply the transaction: %s", err)
+	}
+
+	from, err := db.Query(fromID)
+	if err != nil {
+		t.Fatalf("Should be able to query the from account: %s", err)
+	}
+	if exp := balance - stx.Value - stx.Tip - gasPrice; from.Balance != exp {
+		t.Fatalf("Should debit the from account, got %d, exp %d", from.Balance, exp)
+	}
+
+	to, err := db.Query(toID)
+	if err != nil {
+		t.Fatalf("Should be able to query the to account: %s", err)
+	}
+	if to.Balance != stx.Value {
+		t.Fatalf("Should credit the to account, got %d, exp %d", to.Balance, stx.Value)
+	}
+
+	if accounts := db.Copy(); len(accounts) != 3 {
+		t.Fatalf("Should only have the from, to and beneficiary accounts, got %d", len(accounts))
+	}
+}
+
+func sign(t *testing.T, pk *ecdsa.PrivateKey, tx database.Tx) database.SignedTx {
+	stx, err := tx.Sign(pk)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	return stx
+}
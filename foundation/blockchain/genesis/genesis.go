@@ -1,9 +1,22 @@
+// Package genesis maintains access to the genesis file.
 package genesis
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"os"
 	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/signature"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The range of difficulty values that are considered sane. The difficulty
+// is the number of leading zeros required in a block hash.
+const (
+	minDifficulty = 1
+	maxDifficulty = 16
 )
 
 // read the genesis.json file and mapping it to a go struct
@@ -17,12 +30,13 @@ type Genesis struct {
 	MiningReward  int64             `json:"mining_reward"`
 	GasPrice      int64             `json:"gas_price"`
 	Balances      map[string]uint64 `json:"balances"`
+	Signers       []string          `json:"signers,omitempty"`
 }
 
-// Load open and consume the genesis.json file
-func Load() (Genesis, error) {
-	// read the genesis.json file
-	path := "zblock/genesis.json"
+// Load open and consume the genesis file at the specified path and
+// validates the contents.
+func Load(path string) (Genesis, error) {
+	// read the genesis file
 	content, err := os.ReadFile(path)
 	if err != nil {
 		return Genesis{}, err
@@ -34,5 +48,68 @@ func Load() (Genesis, error) {
 		return Genesis{}, err
 	}
 
+	if err := genesis.Validate(); err != nil {
+		return Genesis{}, fmt.Errorf("validating %s: %w", path, err)
+	}
+
 	return genesis, nil
 }
+
+// Validate checks the genesis values are usable for running a blockchain.
+func (g Genesis) Validate() error {
+	if g.ChainID <= 0 {
+		return errors.New("chain id must be greater than zero")
+	}
+	if g.TransPerBlock <= 0 {
+		return errors.New("trans per block must be greater than zero")
+	}
+	// The difficulty is required even when signers are listed, since the
+	// same genesis can be run under proof of work.
+	if g.Difficulty < minDifficulty || g.Difficulty > maxDifficulty {
+		return fmt.Errorf("difficulty must be between %d and %d, got %d", minDifficulty, maxDifficulty, g.Difficulty)
+	}
+	if g.MiningReward < 0 {
+		return errors.New("mining reward can't be negative")
+	}
+	if g.GasPrice < 0 {
+		return errors.New("gas price can't be negative")
+	}
+
+	// Accounts are not case sensitive, so the same account written in a
+	// different case would have its balance silently replaced.
+	funded := make(map[common.Address]string)
+	for account := range g.Balances {
+		if !common.IsHexAddress(account) {
+			return fmt.Errorf("balance account %q is not a valid account id", account)
+		}
+
+		address := common.HexToAddress(account)
+		if other, exists := funded[address]; exists {
+			return fmt.Errorf("balance account %q is the same account as %q", account, other)
+		}
+		funded[address] = account
+	}
+
+	// The signers are the accounts authorized to produce blocks when running
+	// proof of authority. They take turns in the order listed.
+	seen := make(map[common.Address]bool)
+	for _, signer := range g.Signers {
+		if !common.IsHexAddress(signer) {
+			return fmt.Errorf("signer account %q is not a valid account id", signer)
+		}
+
+		address := common.HexToAddress(signer)
+		if seen[address] {
+			return fmt.Errorf("signer account %q is listed more than once", signer)
+		}
+		seen[address] = true
+	}
+
+	return nil
+}
+
+// Hash returns a unique hash for the genesis values. Nodes running with a
+// different genesis will produce a different hash.
+func (g Genesis) Hash() string {
+	return signature.Hash(g)
+}
@@ -0,0 +1,64 @@
+package genesis_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+)
+
+func TestValidate(t *testing.T) {
+	tt := []struct {
+		name     string
+		balances map[string]uint64
+		signers  []string
+		valid    bool
+	}{
+		{
+			name: "valid",
+			balances: map[string]uint64{
+				"0xF01813E4B85e178A83e29B8E7bF26BD830a25f32": 1000,
+				"0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4": 1000,
+			},
+			signers: []string{"0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8"},
+			valid:   true,
+		},
+		{
+			name: "duplicate balance with different case",
+			balances: map[string]uint64{
+				"0xF01813E4B85e178A83e29B8E7bF26BD830a25f32": 1000,
+				"0xf01813e4b85e178a83e29b8e7bf26bd830a25f32": 1000000,
+			},
+		},
+		{
+			name:     "invalid balance account",
+			balances: map[string]uint64{"0xF01813E4": 1000},
+		},
+		{
+			name:    "duplicate signer with different case",
+			signers: []string{"0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8", "0xfef311483cc040e1a89fb9bb469eeb8a70935ef8"},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			gen := genesis.Genesis{
+				ChainID:       1,
+				TransPerBlock: 10,
+				Difficulty:    6,
+				MiningReward:  700,
+				GasPrice:      15,
+				Balances:      tst.balances,
+				Signers:       tst.signers,
+			}
+
+			err := gen.Validate()
+
+			switch {
+			case tst.valid && err != nil:
+				t.Fatalf("Should be able to validate the genesis: %s", err)
+			case !tst.valid && err == nil:
+				t.Fatalf("Should not be able to validate the genesis")
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package mempool maintains the mempool for the blockchain.
+package mempool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool/selector"
+)
+
+// Mempool represents a cache of transactions organized by account:nonce.
+type Mempool struct {
+	mu       sync.RWMutex
+	pool     map[string]database.BlockTx
+	selectFn selector.Func
+}
+
+// New constructs a new mempool using the default sort strategy.
+func New() (*Mempool, error) {
+	return NewWithStrategy(selector.StrategyTip)
+}
+
+// NewWithStrategy constructs a new mempool with specified sort strategy.
+func NewWithStrategy(strategy string) (*Mempool, error) {
+	selectFn, err := selector.Retrieve(strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	mp := Mempool{
+		pool:     make(map[string]database.BlockTx),
+		selectFn: selectFn,
+	}
+
+	return &mp, nil
+}
+
+// Count returns the current number of transactions in the pool.
+func (mp *Mempool) Count() int {
+	mp.mu.RLock()
+	defer mp.mu.RUnlock()
+
+	return len(mp.pool)
+}
+
+// Upsert adds or replaces a transaction from the mempool. A transaction
+// with the same account and nonce is only replaced when the new transaction
+// is offering a higher tip.
+func (mp *Mempool) Upsert(tx database.BlockTx) error {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	key := mapKey(tx)
+
+	if etx, exists := mp.pool[key]; exists {
+		if tx.Tip <= etx.Tip {
+			return errors.New("replacing a transaction requires a higher tip")
+		}
+	}
+
+	mp.pool[key] = tx
+
+	return nil
+}
+
+// Delete removes a transaction from the mempool.
+func (mp *Mempool) Delete(tx database.BlockTx) {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	delete(mp.pool, mapKey(tx))
+}
+
+// Truncate clears all the transactions from the pool.
+func (mp *Mempool) Truncate() {
+	mp.mu.Lock()
+	defer mp.mu.Unlock()
+
+	mp.pool = make(map[string]database.BlockTx)
+}
+
+// PickBest uses the configured sort strategy to return the next set of
+// transactions for the next block. If howMany is not provided, or is less
+// than 1, all the transactions in the pool are returned. The caller is
+// expected to pass the genesis TransPerBlock value as the cap.
+func (mp *Mempool) PickBest(howMany ...uint16) []database.BlockTx {
+	number := 0
+	if len(howMany) > 0 {
+		number = int(howMany[0])
+	}
+
+	// Group the transactions by account so the select strategy can
+	// respect the nonce ordering of each account.
+	m := make(map[database.AccountID][]database.BlockTx)
+	mp.mu.RLock()
+	{
+		for _, tx := range mp.pool {
+			from := tx.FromID.Normalize()
+			m[from] = append(m[from], tx)
+		}
+	}
+	mp.mu.RUnlock()
+
+	return mp.selectFn(m, number)
+}
+
+// =============================================================================
+
+// mapKey is used to generate the map key. The account is normalized since
+// the same account can arrive with a different case from a peer.
+func mapKey(tx database.BlockTx) string {
+	return fmt.Sprintf("%s:%d", tx.FromID.Normalize(), tx.Nonce)
+}
@@ -0,0 +1,168 @@
+package mempool_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool"
+)
+
+func TestUpsert(t *testing.T) {
+	tt := []struct {
+		name   string
+		txs    []database.BlockTx
+		valid  []bool
+		expTip uint64
+	}{
+		{
+			name:   "higher tip replaces",
+			txs:    []database.BlockTx{blockTx("A", 1, 10), blockTx("A", 1, 20)},
+			valid:  []bool{true, true},
+			expTip: 20,
+		},
+		{
+			name:   "same tip is refused",
+			txs:    []database.BlockTx{blockTx("A", 1, 10), blockTx("A", 1, 10)},
+			valid:  []bool{true, false},
+			expTip: 10,
+		},
+		{
+			name:   "account case doesn't matter",
+			txs:    []database.BlockTx{blockTx(lowerID, 1, 10), blockTx(checksumID, 1, 20)},
+			valid:  []bool{true, true},
+			expTip: 20,
+		},
+		{
+			name:   "lower tip is refused",
+			txs:    []database.BlockTx{blockTx("A", 1, 10), blockTx("A", 1, 5)},
+			valid:  []bool{true, false},
+			expTip: 10,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			mp, err := mempool.New()
+			if err != nil {
+				t.Fatalf("Should be able to construct the mempool: %s", err)
+			}
+
+			for i, tx := range tst.txs {
+				err := mp.Upsert(tx)
+
+				switch {
+				case tst.valid[i] && err != nil:
+					t.Fatalf("Should be able to upsert tx %d: %s", i, err)
+				case !tst.valid[i] && err == nil:
+					t.Fatalf("Should not be able to upsert tx %d", i)
+				}
+			}
+
+			if count := mp.Count(); count != 1 {
+				t.Fatalf("Should have one transaction, got %d", count)
+			}
+
+			if tip := mp.PickBest()[0].Tip; tip != tst.expTip {
+				t.Fatalf("Should keep the expected transaction, got tip %d, exp %d", tip, tst.expTip)
+			}
+		})
+	}
+}
+
+func TestPickBest(t *testing.T) {
+	mp, err := mempool.New()
+	if err != nil {
+		t.Fatalf("Should be able to construct the mempool: %s", err)
+	}
+
+	// The later nonces of account A pay more, but can't be picked before
+	// the first nonce.
+	txs := []database.BlockTx{
+		blockTx("A", 3, 30),
+		blockTx("A", 2, 20),
+		blockTx("B", 1, 15),
+		blockTx("A", 1, 10),
+	}
+	for _, tx := range txs {
+		if err := mp.Upsert(tx); err != nil {
+			t.Fatalf("Should be able to upsert the transaction: %s", err)
+		}
+	}
+
+	tt := []struct {
+		name    string
+		howMany []uint16
+		exp     []string
+	}{
+		{name: "all", exp: []string{"B:1", "A:1", "A:2", "A:3"}},
+		{name: "zero is all", howMany: []uint16{0}, exp: []string{"B:1", "A:1", "A:2", "A:3"}},
+		{name: "two", howMany: []uint16{2}, exp: []string{"B:1", "A:1"}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			got := mp.PickBest(tst.howMany...)
+
+			if len(got) != len(tst.exp) {
+				t.Fatalf("Should get the expected number of transactions, got %d, exp %d", len(got), len(tst.exp))
+			}
+			for i, tx := range got {
+				if tx.String() != tst.exp[i] {
+					t.Fatalf("Should get the expected transaction at %d, got %s, exp %s", i, tx, tst.exp[i])
+				}
+			}
+		})
+	}
+
+	mp.Delete(blockTx("A", 1, 0))
+	if count := mp.Count(); count != 3 {
+		t.Fatalf("Should remove the transaction, got %d transactions", count)
+	}
+}
+
+func TestDeleteMixedCase(t *testing.T) {
+	mp, err := mempool.New()
+	if err != nil {
+		t.Fatalf("Should be able to construct the mempool: %s", err)
+	}
+
+	// The nonces of one account arrive with different cases, but must still
+	// be picked in nonce order.
+	txs := []database.BlockTx{blockTx(lowerID, 2, 20), blockTx(checksumID, 1, 10)}
+	for _, tx := range txs {
+		if err := mp.Upsert(tx); err != nil {
+			t.Fatalf("Should be able to upsert the transaction: %s", err)
+		}
+	}
+
+	got := mp.PickBest(1)
+	if len(got) != 1 || got[0].Nonce != 1 {
+		t.Fatalf("Should pick the first nonce of the account, got %v", got)
+	}
+
+	// A block from a peer can carry the account with another case than the
+	// transaction in the mempool.
+	mp.Delete(blockTx(upperID, 1, 0))
+	mp.Delete(blockTx(checksumID, 2, 0))
+
+	if count := mp.Count(); count != 0 {
+		t.Fatalf("Should remove the transactions, got %d transactions", count)
+	}
+}
+
+// =============================================================================
+
+// The same account written with different cases.
+const (
+	checksumID = database.AccountID("0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4")
+	lowerID    = database.AccountID("0xdd6b972ffcc631a62cae1bb9d80b7ff429c8eba4")
+	upperID    = database.AccountID("0xDD6B972FFCC631A62CAE1BB9D80B7FF429C8EBA4")
+)
+
+func blockTx(from database.AccountID, nonce uint64, tip uint64) database.BlockTx {
+	return database.BlockTx{
+		SignedTx: database.SignedTx{
+			Tx: database.Tx{FromID: from, Nonce: nonce, Tip: tip},
+		},
+	}
+}
@@ -0,0 +1,90 @@
+package selector
+
+import (
+	"sort"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// advancedTipSelect returns transactions with the best tip while respecting
+// the nonce for each account/transaction. Since an account's transactions
+// must be taken in nonce order, a low tip transaction can unlock high tip
+// transactions behind it. This strategy looks at every nonce-chained group
+// and finds the combination that maximizes the total tip for the block.
+var advancedTipSelect = func(m map[database.AccountID][]database.BlockTx, howMany int) []database.BlockTx {
+	m = queues(m)
+
+	if howMany <= 0 {
+		return merge(m, 0, byTip)
+	}
+
+	// Order the accounts so the result is deterministic.
+	accounts := make([]database.AccountID, 0, len(m))
+	for from := range m {
+		accounts = append(accounts, from)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i] < accounts[j]
+	})
+
+	// best[n] holds the highest total tip that can be collected using n
+	// transactions from the accounts processed so far. take[i][n] records
+	// how many transactions account i contributed to reach best[n].
+	best := make([]uint64, howMany+1)
+	take := make([][]int, len(accounts))
+
+	for i, from := range accounts {
+		txs := m[from]
+
+		// Calculate the tip collected by taking the first k transactions.
+		prefix := make([]uint64, len(txs)+1)
+		for k, tx := range txs {
+			prefix[k+1] = prefix[k] + tx.Tip
+		}
+
+		next := make([]uint64, howMany+1)
+		take[i] = make([]int, howMany+1)
+		for n := 0; n <= howMany; n++ {
+			next[n] = best[n]
+			for k := 1; k <= len(txs) && k <= n; k++ {
+				if tip := best[n-k] + prefix[k]; tip > next[n] {
+					next[n] = tip
+					take[i][n] = k
+				}
+			}
+		}
+		best = next
+	}
+
+	// Find the smallest number of transactions that provides the best total
+	// tip and then fill any remaining space with what is left.
+	n := 0
+	for i := range best {
+		if best[i] > best[n] {
+			n = i
+		}
+	}
+
+	selected := make(map[database.AccountID][]database.BlockTx)
+	for i := len(accounts) - 1; i >= 0; i-- {
+		k := take[i][n]
+		if k == 0 {
+			continue
+		}
+
+		from := accounts[i]
+		selected[from] = m[from][:k]
+		m[from] = m[from][k:]
+		if len(m[from]) == 0 {
+			delete(m, from)
+		}
+		n -= k
+	}
+
+	final := merge(selected, 0, byTip)
+	if len(final) < howMany {
+		final = append(final, merge(m, howMany-len(final), byTip)...)
+	}
+
+	return final
+}
@@ -0,0 +1,16 @@
+package selector
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// fifoSelect returns transactions in the order they arrived at the node while
+// respecting the nonce for each account/transaction.
+var fifoSelect = func(m map[database.AccountID][]database.BlockTx, howMany int) []database.BlockTx {
+	return merge(queues(m), howMany, byArrival)
+}
+
+// byArrival orders transactions by the oldest timestamp first.
+func byArrival(a, b database.BlockTx) bool {
+	return a.TimeStamp < b.TimeStamp
+}
@@ -0,0 +1,118 @@
+// Package selector provides different transaction selecting algorithms.
+package selector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// List of different select strategies.
+const (
+	StrategyTip         = "tip"
+	StrategyTipAdvanced = "tip_advanced"
+	StrategyFIFO        = "fifo"
+)
+
+// Map of different select strategies with functions.
+var strategies = map[string]Func{
+	StrategyTip:         tipSelect,
+	StrategyTipAdvanced: advancedTipSelect,
+	StrategyFIFO:        fifoSelect,
+}
+
+// Func defines a function that takes a mempool of transactions grouped by
+// account and selects howMany of them in an order based on the functions
+// strategy. All selector functions MUST respect nonce ordering. Receiving 0
+// for howMany must return all the transactions in the strategies ordering.
+type Func func(transactions map[database.AccountID][]database.BlockTx, howMany int) []database.BlockTx
+
+// Retrieve returns the specified select strategy function.
+func Retrieve(strategy string) (Func, error) {
+	fn, exists := strategies[strings.ToLower(strategy)]
+	if !exists {
+		return nil, fmt.Errorf("strategy %q does not exist", strategy)
+	}
+
+	return fn, nil
+}
+
+// =============================================================================
+
+// byNonce provides sorting support by the transaction id value.
+type byNonce []database.BlockTx
+
+// Len returns the number of transactions in the list.
+func (bn byNonce) Len() int {
+	return len(bn)
+}
+
+// Less helps to sort the list by nonce in ascending order to keep the
+// transactions in the right order of processing.
+func (bn byNonce) Less(i, j int) bool {
+	return bn[i].Nonce < bn[j].Nonce
+}
+
+// Swap moves transactions in the order of the nonce value.
+func (bn byNonce) Swap(i, j int) {
+	bn[i], bn[j] = bn[j], bn[i]
+}
+
+// =============================================================================
+
+// queues makes a copy of the transactions for each account sorted by nonce
+// so the selectors can consume them without touching the caller's data.
+func queues(transactions map[database.AccountID][]database.BlockTx) map[database.AccountID][]database.BlockTx {
+	m := make(map[database.AccountID][]database.BlockTx, len(transactions))
+	for from, txs := range transactions {
+		if len(txs) == 0 {
+			continue
+		}
+
+		queue := make([]database.BlockTx, len(txs))
+		copy(queue, txs)
+		sort.Sort(byNonce(queue))
+
+		m[from] = queue
+	}
+
+	return m
+}
+
+// merge repeatedly takes the transaction at the front of the account queue
+// chosen by the before function until howMany transactions are selected or
+// the queues are empty. Ties are broken by account so the result is
+// deterministic.
+func merge(m map[database.AccountID][]database.BlockTx, howMany int, before func(a, b database.BlockTx) bool) []database.BlockTx {
+	if howMany <= 0 {
+		for _, txs := range m {
+			howMany += len(txs)
+		}
+	}
+
+	final := make([]database.BlockTx, 0, howMany)
+	for len(final) < howMany && len(m) > 0 {
+		var best database.AccountID
+		for from, txs := range m {
+			switch {
+			case best == "":
+				best = from
+			case before(txs[0], m[best][0]):
+				best = from
+			case !before(m[best][0], txs[0]) && from < best:
+				best = from
+			}
+		}
+
+		final = append(final, m[best][0])
+
+		m[best] = m[best][1:]
+		if len(m[best]) == 0 {
+			delete(m, best)
+		}
+	}
+
+	return final
+}
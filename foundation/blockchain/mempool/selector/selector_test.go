@@ -0,0 +1,168 @@
+package selector_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool/selector"
+)
+
+func TestSelect(t *testing.T) {
+	type tx struct {
+		from      database.AccountID
+		nonce     uint64
+		tip       uint64
+		timeStamp uint64
+	}
+
+	tt := []struct {
+		name     string
+		strategy string
+		txs      []tx
+		howMany  int
+		exp      []string
+	}{
+		{
+			name:     "tip takes the best tip at each step",
+			strategy: selector.StrategyTip,
+			txs:      []tx{{"A", 1, 1, 1}, {"A", 2, 50, 2}, {"B", 1, 10, 3}},
+			howMany:  2,
+			exp:      []string{"B:1", "A:1"},
+		},
+		{
+			name:     "tip returns everything for zero",
+			strategy: selector.StrategyTip,
+			txs:      []tx{{"A", 1, 1, 1}, {"A", 2, 50, 2}, {"B", 1, 10, 3}},
+			exp:      []string{"B:1", "A:1", "A:2"},
+		},
+		{
+			name:     "tip respects the nonce order",
+			strategy: selector.StrategyTip,
+			txs:      []tx{{"A", 3, 30, 1}, {"A", 1, 10, 2}, {"A", 2, 20, 3}},
+			howMany:  3,
+			exp:      []string{"A:1", "A:2", "A:3"},
+		},
+		{
+			name:     "tip breaks ties by account",
+			strategy: selector.StrategyTip,
+			txs:      []tx{{"B", 1, 10, 1}, {"A", 1, 10, 2}},
+			howMany:  2,
+			exp:      []string{"A:1", "B:1"},
+		},
+		{
+			name:     "tip advanced unlocks a high tip behind a low tip",
+			strategy: selector.StrategyTipAdvanced,
+			txs:      []tx{{"A", 1, 1, 1}, {"A", 2, 50, 2}, {"B", 1, 10, 3}},
+			howMany:  2,
+			exp:      []string{"A:1", "A:2"},
+		},
+		{
+			name:     "tip advanced combines accounts",
+			strategy: selector.StrategyTipAdvanced,
+			txs:      []tx{{"A", 1, 1, 1}, {"A", 2, 50, 2}, {"A", 3, 1, 3}, {"B", 1, 40, 4}, {"B", 2, 1, 5}, {"C", 1, 30, 6}},
+			howMany:  3,
+			exp:      []string{"B:1", "A:1", "A:2"},
+		},
+		{
+			name:     "tip advanced fills the block with zero tips",
+			strategy: selector.StrategyTipAdvanced,
+			txs:      []tx{{"A", 1, 0, 1}, {"B", 1, 5, 2}},
+			howMany:  2,
+			exp:      []string{"B:1", "A:1"},
+		},
+		{
+			name:     "tip advanced returns everything for zero",
+			strategy: selector.StrategyTipAdvanced,
+			txs:      []tx{{"A", 2, 50, 1}, {"A", 1, 1, 2}, {"B", 1, 10, 3}},
+			exp:      []string{"B:1", "A:1", "A:2"},
+		},
+		{
+			name:     "tip advanced with more room than transactions",
+			strategy: selector.StrategyTipAdvanced,
+			txs:      []tx{{"A", 1, 1, 1}, {"B", 1, 10, 2}},
+			howMany:  10,
+			exp:      []string{"B:1", "A:1"},
+		},
+		{
+			name:     "fifo takes the oldest first",
+			strategy: selector.StrategyFIFO,
+			txs:      []tx{{"A", 1, 50, 3}, {"B", 1, 1, 1}, {"C", 1, 10, 2}},
+			howMany:  2,
+			exp:      []string{"B:1", "C:1"},
+		},
+		{
+			name:     "fifo respects the nonce order",
+			strategy: selector.StrategyFIFO,
+			txs:      []tx{{"A", 1, 0, 3}, {"A", 2, 0, 1}, {"B", 1, 0, 2}},
+			exp:      []string{"B:1", "A:1", "A:2"},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			fn, err := selector.Retrieve(tst.strategy)
+			if err != nil {
+				t.Fatalf("Should be able to retrieve the strategy: %s", err)
+			}
+
+			m := make(map[database.AccountID][]database.BlockTx)
+			for _, tx := range tst.txs {
+				blockTx := database.BlockTx{
+					SignedTx: database.SignedTx{
+						Tx: database.Tx{FromID: tx.from, Nonce: tx.nonce, Tip: tx.tip},
+					},
+					TimeStamp: tx.timeStamp,
+				}
+				m[tx.from] = append(m[tx.from], blockTx)
+			}
+
+			got := fn(m, tst.howMany)
+
+			if len(got) != len(tst.exp) {
+				t.Fatalf("Should get the expected number of transactions, got %d, exp %d", len(got), len(tst.exp))
+			}
+			for i, tx := range got {
+				if id := fmt.Sprintf("%s:%d", tx.FromID, tx.Nonce); id != tst.exp[i] {
+					t.Fatalf("Should get the expected transaction at %d, got %s, exp %s", i, id, tst.exp[i])
+				}
+			}
+
+			// The selectors must not change the caller's transactions.
+			seen := make(map[database.AccountID]int)
+			for _, tx := range tst.txs {
+				i := seen[tx.from]
+				seen[tx.from]++
+				if i >= len(m[tx.from]) || m[tx.from][i].Nonce != tx.nonce {
+					t.Fatalf("Should not change the transactions of %s in the caller's map", tx.from)
+				}
+			}
+		})
+	}
+}
+
+func TestRetrieve(t *testing.T) {
+	tt := []struct {
+		strategy string
+		valid    bool
+	}{
+		{strategy: selector.StrategyTip, valid: true},
+		{strategy: selector.StrategyTipAdvanced, valid: true},
+		{strategy: selector.StrategyFIFO, valid: true},
+		{strategy: "TIP", valid: true},
+		{strategy: "random"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.strategy, func(t *testing.T) {
+			_, err := selector.Retrieve(tst.strategy)
+
+			switch {
+			case tst.valid && err != nil:
+				t.Fatalf("Should be able to retrieve the strategy: %s", err)
+			case !tst.valid && err == nil:
+				t.Fatalf("Should not be able to retrieve an unknown strategy")
+			}
+		})
+	}
+}
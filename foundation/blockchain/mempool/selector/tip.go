@@ -0,0 +1,17 @@
+package selector
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// tipSelect returns transactions with the best tip while respecting the nonce
+// for each account/transaction. At each step the next transaction of every
+// account is considered and the one paying the highest tip is taken.
+var tipSelect = func(m map[database.AccountID][]database.BlockTx, howMany int) []database.BlockTx {
+	return merge(queues(m), howMany, byTip)
+}
+
+// byTip orders transactions by the highest tip first.
+func byTip(a, b database.BlockTx) bool {
+	return a.Tip > b.Tip
+}
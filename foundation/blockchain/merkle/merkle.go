@@ -0,0 +1,348 @@
+// Package merkle provides a generic merkle tree implementation for any value
+// that can provide a hash and be compared for equality. Proofs generated by
+// the tree can be verified without access to the tree, which allows clients
+// to prove a value is part of a set knowing only the merkle root.
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+)
+
+// Proof order values describe where the proof hash is placed when joining
+// it with the running hash during verification.
+const (
+	ProofLeft  = 0
+	ProofRight = 1
+)
+
+// Hashable represents the behavior concrete data must exhibit to be used in
+// the merkle tree.
+type Hashable[T any] interface {
+	Hash() ([]byte, error)
+	Equal(other T) bool
+}
+
+// =============================================================================
+
+// Tree represents a merkle tree that uses data of some type T that exhibits the
+// behavior defined by the Hashable constraint.
+type Tree[T Hashable[T]] struct {
+	Root         *Node[T]
+	Leafs        []*Node[T]
+	MerkleRoot   []byte
+	hashStrategy func() hash.Hash
+}
+
+// WithHashStrategy is used to change the default hash strategy of using
+// sha256 when constructing a new tree.
+func WithHashStrategy[T Hashable[T]](hashStrategy func() hash.Hash) func(t *Tree[T]) {
+	return func(t *Tree[T]) {
+		t.hashStrategy = hashStrategy
+	}
+}
+
+// NewTree constructs a new merkle tree that uses data of some type T that
+// exhibits the behavior defined by the Hashable interface.
+func NewTree[T Hashable[T]](values []T, options ...func(t *Tree[T])) (*Tree[T], error) {
+	t := Tree[T]{
+		hashStrategy: sha256.New,
+	}
+
+	for _, option := range options {
+		option(&t)
+	}
+
+	if err := t.Generate(values); err != nil {
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Generate constructs the leafs and nodes of the tree from the specified
+// data, replacing whatever the tree held before. If the data set has an odd
+// number of values, the last value is duplicated to balance the tree.
+func (t *Tree[T]) Generate(values []T) error {
+	if len(values) == 0 {
+		return errors.New("cannot construct tree with no content")
+	}
+
+	var leafs []*Node[T]
+	for _, value := range values {
+		hash, err := value.Hash()
+		if err != nil {
+			return err
+		}
+
+		leafs = append(leafs, &Node[T]{
+			Hash:  hash,
+			Value: value,
+			leaf:  true,
+			Tree:  t,
+		})
+	}
+
+	if len(leafs)%2 == 1 {
+		duplicate := &Node[T]{
+			Hash:  leafs[len(leafs)-1].Hash,
+			Value: leafs[len(leafs)-1].Value,
+			leaf:  true,
+			dup:   true,
+			Tree:  t,
+		}
+		leafs = append(leafs, duplicate)
+	}
+
+	root, err := buildIntermediate(leafs, t)
+	if err != nil {
+		return err
+	}
+
+	t.Root = root
+	t.Leafs = leafs
+	t.MerkleRoot = root.Hash
+
+	return nil
+}
+
+// Rebuild is a helper function that will rebuild the tree reusing only the
+// data that it currently holds in the leaves. This is used when the values
+// stored in the leaves have changed.
+func (t *Tree[T]) Rebuild() error {
+	return t.Generate(t.Values())
+}
+
+// Verify validates the hashes at each level of the tree and returns an error
+// if the resulting hash at the root of the tree doesn't match the stored
+// merkle root.
+func (t *Tree[T]) Verify() error {
+	calculatedMerkleRoot, err := t.Root.verify()
+	if err != nil {
+		return err
+	}
+
+	if !bytes.Equal(t.MerkleRoot, calculatedMerkleRoot) {
+		return errors.New("root hash invalid")
+	}
+
+	return nil
+}
+
+// VerifyData indicates whether a given piece of data is in the tree and that
+// the hashes are valid for that data along the path to the root.
+func (t *Tree[T]) VerifyData(data T) error {
+	leaf, err := t.Leaf(data)
+	if err != nil {
+		return err
+	}
+
+	h := t.hashStrategy()
+	for parent := leaf.Parent; parent != nil; parent = parent.Parent {
+		h.Reset()
+		h.Write(append(append([]byte{}, parent.Left.Hash...), parent.Right.Hash...))
+		if !bytes.Equal(h.Sum(nil), parent.Hash) {
+			return errors.New("merkle hash is not equal")
+		}
+	}
+
+	return nil
+}
+
+// Leaf returns the leaf node that holds the specified data.
+func (t *Tree[T]) Leaf(data T) (*Node[T], error) {
+	for _, leaf := range t.Leafs {
+		if leaf.Value.Equal(data) {
+			return leaf, nil
+		}
+	}
+
+	return nil, errors.New("data not found in tree")
+}
+
+// Proof returns the set of hashes and the order of concatenating those
+// hashes for proving a transaction is in the tree. The order values are
+// ProofLeft when the proof hash must be placed before the running hash and
+// ProofRight when it must be placed after.
+func (t *Tree[T]) Proof(data T) ([][]byte, []int64, error) {
+	leaf, err := t.Leaf(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var merklePath [][]byte
+	var order []int64
+
+	current := leaf
+	for parent := current.Parent; parent != nil; parent = parent.Parent {
+		if parent.Left == current {
+			merklePath = append(merklePath, parent.Right.Hash)
+			order = append(order, ProofRight)
+		} else {
+			merklePath = append(merklePath, parent.Left.Hash)
+			order = append(order, ProofLeft)
+		}
+		current = parent
+	}
+
+	return merklePath, order, nil
+}
+
+// Values returns a slice of unique values stored in the tree. The duplicate
+// value added to balance the tree is not included.
+func (t *Tree[T]) Values() []T {
+	var values []T
+	for _, tree := range t.Leafs {
+		if tree.dup {
+			continue
+		}
+		values = append(values, tree.Value)
+	}
+
+	return values
+}
+
+// RootHex converts the merkle root byte hash to a hex encoded string.
+func (t *Tree[T]) RootHex() string {
+	return hexutil.Encode(t.MerkleRoot)
+}
+
+// String returns a string representation of the tree. Only leaf nodes are
+// included in the output.
+func (t *Tree[T]) String() string {
+	s := ""
+	for _, l := range t.Leafs {
+		s += fmt.Sprint(l)
+		s += "\n"
+	}
+	return s
+}
+
+// =============================================================================
+
+// VerifyProof checks the specified leaf hash is part of the tree identified
+// by the merkle root using the proof and order returned by Proof. The tree
+// itself is not required, which is what allows light clients to verify a
+// value with only a block header. The sha256 hash strategy is used.
+func VerifyProof(merkleRoot []byte, leafHash []byte, proof [][]byte, order []int64) error {
+	return VerifyProofWithHashStrategy(sha256.New, merkleRoot, leafHash, proof, order)
+}
+
+// VerifyProofWithHashStrategy performs the same validation as VerifyProof
+// using the specified hash strategy.
+func VerifyProofWithHashStrategy(hashStrategy func() hash.Hash, merkleRoot []byte, leafHash []byte, proof [][]byte, order []int64) error {
+	if len(proof) != len(order) {
+		return errors.New("proof and order length mismatch")
+	}
+
+	h := hashStrategy()
+	current := leafHash
+	for i := range proof {
+		h.Reset()
+		switch order[i] {
+		case ProofLeft:
+			h.Write(append(append([]byte{}, proof[i]...), current...))
+		case ProofRight:
+			h.Write(append(append([]byte{}, current...), proof[i]...))
+		default:
+			return fmt.Errorf("invalid proof order value %d", order[i])
+		}
+		current = h.Sum(nil)
+	}
+
+	if !bytes.Equal(current, merkleRoot) {
+		return errors.New("proof does not match merkle root")
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// Node represents a node, root, or leaf in the tree. It stores pointers to
+// its immediate relationships, a hash, the data if it is a leaf, and other
+// metadata.
+type Node[T Hashable[T]] struct {
+	Tree   *Tree[T]
+	Parent *Node[T]
+	Left   *Node[T]
+	Right  *Node[T]
+	Hash   []byte
+	Value  T
+	leaf   bool
+	dup    bool
+}
+
+// verify walks down the tree until hitting a leaf, calculating the hash at
+// each level and returning the resulting hash of the node.
+func (n *Node[T]) verify() ([]byte, error) {
+	if n.leaf {
+		return n.Value.Hash()
+	}
+
+	rightBytes, err := n.Right.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	leftBytes, err := n.Left.verify()
+	if err != nil {
+		return nil, err
+	}
+
+	h := n.Tree.hashStrategy()
+	if _, err := h.Write(append(leftBytes, rightBytes...)); err != nil {
+		return nil, err
+	}
+
+	return h.Sum(nil), nil
+}
+
+// String returns a string representation of the node.
+func (n *Node[T]) String() string {
+	return fmt.Sprintf("%t %t %v %s", n.leaf, n.dup, n.Hash, fmt.Sprint(n.Value))
+}
+
+// =============================================================================
+
+// buildIntermediate is a helper function that for a given list of leaf nodes,
+// constructs the intermediate and root levels of the tree. Returns the
+// resulting root node of the tree.
+func buildIntermediate[T Hashable[T]](nl []*Node[T], t *Tree[T]) (*Node[T], error) {
+	var nodes []*Node[T]
+
+	for i := 0; i < len(nl); i += 2 {
+		var left, right int = i, i + 1
+		if i+1 == len(nl) {
+			right = i
+		}
+
+		h := t.hashStrategy()
+		chash := append(append([]byte{}, nl[left].Hash...), nl[right].Hash...)
+		if _, err := h.Write(chash); err != nil {
+			return nil, err
+		}
+
+		n := Node[T]{
+			Left:  nl[left],
+			Right: nl[right],
+			Hash:  h.Sum(nil),
+			Tree:  t,
+		}
+
+		nodes = append(nodes, &n)
+		nl[left].Parent = &n
+		nl[right].Parent = &n
+
+		if len(nl) == 2 {
+			return &n, nil
+		}
+	}
+
+	return buildIntermediate(nodes, t)
+}
@@ -0,0 +1,230 @@
+package merkle_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/merkle"
+)
+
+// data is a value that can be stored in the tree.
+type data string
+
+func (d data) Hash() ([]byte, error) {
+	h := sha256.Sum256([]byte(d))
+	return h[:], nil
+}
+
+func (d data) Equal(other data) bool {
+	return d == other
+}
+
+// =============================================================================
+
+func TestProof(t *testing.T) {
+	tt := []struct {
+		name  string
+		count int
+	}{
+		{name: "one value", count: 1},
+		{name: "two values", count: 2},
+		{name: "three values", count: 3},
+		{name: "four values", count: 4},
+		{name: "five values", count: 5},
+		{name: "seven values", count: 7},
+		{name: "eight values", count: 8},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			values := newValues(tst.count)
+
+			tree, err := merkle.NewTree(values)
+			if err != nil {
+				t.Fatalf("Should be able to construct the tree: %s", err)
+			}
+
+			if err := tree.Verify(); err != nil {
+				t.Fatalf("Should be able to verify the tree: %s", err)
+			}
+
+			if got := tree.Values(); len(got) != len(values) {
+				t.Fatalf("Should get the values without the duplicate, got %d, exp %d", len(got), len(values))
+			}
+
+			for _, value := range values {
+				if err := tree.VerifyData(value); err != nil {
+					t.Fatalf("Should be able to verify the data %s: %s", value, err)
+				}
+
+				proof, order, err := tree.Proof(value)
+				if err != nil {
+					t.Fatalf("Should be able to get the proof for %s: %s", value, err)
+				}
+
+				leafHash, _ := value.Hash()
+				if err := merkle.VerifyProof(tree.MerkleRoot, leafHash, proof, order); err != nil {
+					t.Fatalf("Should be able to verify the proof for %s: %s", value, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleRoot(t *testing.T) {
+	a, b, c := data("a"), data("b"), data("c")
+	ha, _ := a.Hash()
+	hb, _ := b.Hash()
+	hc, _ := c.Hash()
+
+	tt := []struct {
+		name   string
+		values []data
+		exp    []byte
+	}{
+		{
+			name:   "two values",
+			values: []data{a, b},
+			exp:    hash(ha, hb),
+		},
+		{
+			name:   "odd values duplicate the last value",
+			values: []data{a, b, c},
+			exp:    hash(hash(ha, hb), hash(hc, hc)),
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			tree, err := merkle.NewTree(tst.values)
+			if err != nil {
+				t.Fatalf("Should be able to construct the tree: %s", err)
+			}
+
+			if !bytes.Equal(tree.MerkleRoot, tst.exp) {
+				t.Fatalf("Should get the expected merkle root, got %x, exp %x", tree.MerkleRoot, tst.exp)
+			}
+		})
+	}
+}
+
+func TestVerifyProofFailures(t *testing.T) {
+	values := newValues(5)
+
+	tree, err := merkle.NewTree(values)
+	if err != nil {
+		t.Fatalf("Should be able to construct the tree: %s", err)
+	}
+
+	value := values[2]
+	leafHash, _ := value.Hash()
+
+	proof, order, err := tree.Proof(value)
+	if err != nil {
+		t.Fatalf("Should be able to get the proof: %s", err)
+	}
+
+	tt := []struct {
+		name     string
+		root     []byte
+		leafHash []byte
+		proof    func() [][]byte
+		order    func() []int64
+	}{
+		{
+			name:     "wrong leaf",
+			root:     tree.MerkleRoot,
+			leafHash: hash([]byte("not in the tree")),
+			proof:    func() [][]byte { return proof },
+			order:    func() []int64 { return order },
+		},
+		{
+			name:     "wrong root",
+			root:     hash([]byte("not the root")),
+			leafHash: leafHash,
+			proof:    func() [][]byte { return proof },
+			order:    func() []int64 { return order },
+		},
+		{
+			name:     "tampered proof",
+			root:     tree.MerkleRoot,
+			leafHash: leafHash,
+			proof: func() [][]byte {
+				p := append([][]byte{}, proof...)
+				p[0] = hash(p[0])
+				return p
+			},
+			order: func() []int64 { return order },
+		},
+		{
+			name:     "swapped order",
+			root:     tree.MerkleRoot,
+			leafHash: leafHash,
+			proof:    func() [][]byte { return proof },
+			order: func() []int64 {
+				o := append([]int64{}, order...)
+				o[0] = 1 - o[0]
+				return o
+			},
+		},
+		{
+			name:     "invalid order value",
+			root:     tree.MerkleRoot,
+			leafHash: leafHash,
+			proof:    func() [][]byte { return proof },
+			order: func() []int64 {
+				o := append([]int64{}, order...)
+				o[0] = 2
+				return o
+			},
+		},
+		{
+			name:     "short proof",
+			root:     tree.MerkleRoot,
+			leafHash: leafHash,
+			proof:    func() [][]byte { return proof[1:] },
+			order:    func() []int64 { return order[1:] },
+		},
+		{
+			name:     "length mismatch",
+			root:     tree.MerkleRoot,
+			leafHash: leafHash,
+			proof:    func() [][]byte { return proof },
+			order:    func() []int64 { return order[1:] },
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if err := merkle.VerifyProof(tst.root, tst.leafHash, tst.proof(), tst.order()); err == nil {
+				t.Fatalf("Should not be able to verify the proof")
+			}
+		})
+	}
+
+	if _, _, err := tree.Proof(data("missing")); err == nil {
+		t.Fatalf("Should not be able to get a proof for data not in the tree")
+	}
+}
+
+// =============================================================================
+
+func newValues(n int) []data {
+	values := make([]data, n)
+	for i := range values {
+		values[i] = data(fmt.Sprintf("value %d", i))
+	}
+
+	return values
+}
+
+func hash(parts ...[]byte) []byte {
+	h := sha256.New()
+	for _, part := range parts {
+		h.Write(part)
+	}
+
+	return h.Sum(nil)
+}
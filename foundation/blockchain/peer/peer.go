@@ -0,0 +1,131 @@
+// Package peer maintains the peer related information such as the set
+// of know peers and their status.
+package peer
+
+import (
+	"sync"
+)
+
+// GenesisHashHeader is the HTTP header a node uses to send the hash of its
+// genesis with every request to a peer.
+const GenesisHashHeader = "X-Genesis-Hash"
+
+// maxFailures is the number of consecutive failed requests a peer can have
+// before it is dropped from the peer set.
+const maxFailures = 3
+
+// Peer represents information about a Node in the network.
+type Peer struct {
+	Host string `json:"host"`
+}
+
+// New constructs a new info value.
+func New(host string) Peer {
+	return Peer{
+		Host: host,
+	}
+}
+
+// Match validates if the specified host matches this node.
+func (p Peer) Match(host string) bool {
+	return p.Host == host
+}
+
+// =============================================================================
+
+// PeerStatus represents information about the status
+// of any given peer.
+type PeerStatus struct {
+	GenesisHash       string `json:"genesis_hash"`
+	LatestBlockHash   string `json:"latest_block_hash"`
+	LatestBlockNumber uint64 `json:"latest_block_number"`
+	KnownPeers        []Peer `json:"known_peers"`
+}
+
+// =============================================================================
+
+// PeerSet represents the data representation to maintain a set of known peers.
+// Each peer carries the number of consecutive failed requests made to it.
+type PeerSet struct {
+	mu  sync.RWMutex
+	set map[Peer]int
+}
+
+// NewPeerSet constructs a new info set to manage node peer information.
+func NewPeerSet() *PeerSet {
+	return &PeerSet{
+		set: make(map[Peer]int),
+	}
+}
+
+// Add adds a new node to the set. It reports true when the peer was not
+// already in the set.
+func (ps *PeerSet) Add(peer Peer) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, exists := ps.set[peer]; exists {
+		return false
+	}
+
+	ps.set[peer] = 0
+
+	return true
+}
+
+// Remove removes a node from the set.
+func (ps *PeerSet) Remove(peer Peer) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	delete(ps.set, peer)
+}
+
+// Copy returns a list of the known peers, excluding the peer
+// with the specified host.
+func (ps *PeerSet) Copy(host string) []Peer {
+	ps.mu.RLock()
+	defer ps.mu.RUnlock()
+
+	peers := make([]Peer, 0, len(ps.set))
+	for peer := range ps.set {
+		if !peer.Match(host) {
+			peers = append(peers, peer)
+		}
+	}
+
+	return peers
+}
+
+// Succeeded clears the failure count for the specified peer.
+func (ps *PeerSet) Succeeded(peer Peer) {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	if _, exists := ps.set[peer]; exists {
+		ps.set[peer] = 0
+	}
+}
+
+// Failed records a failed request to the specified peer. Once the peer
+// reaches maxFailures consecutive failures it is removed from the set and
+// true is returned.
+func (ps *PeerSet) Failed(peer Peer) bool {
+	ps.mu.Lock()
+	defer ps.mu.Unlock()
+
+	failures, exists := ps.set[peer]
+	if !exists {
+		return false
+	}
+
+	failures++
+	if failures >= maxFailures {
+		delete(ps.set, peer)
+		return true
+	}
+
+	ps.set[peer] = failures
+
+	return false
+}
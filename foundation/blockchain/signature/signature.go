@@ -17,11 +17,22 @@ const ZeroHash = "0x000000000000000000000000000000000000000000000000000000000000
 // blkcorID is an arbitrary value used to identify the blkcor blockchain and sign to the message.
 const blkcorID = 27
 
-// Hash return the unique hash string of the value.
+// Encoder is implemented by values that provide a canonical binary encoding
+// for signing and hashing. The encoding must carry its own version so the
+// bytes of different encoding versions never collide.
+type Encoder interface {
+	EncodeCanonical() ([]byte, error)
+}
+
+// Hash return the unique hash string of the value. Values implementing the
+// Encoder interface are hashed using their canonical encoding, everything
+// else is hashed using its JSON encoding. If the value can't be encoded, an
+// empty string is returned. It must never be a valid looking hash like the
+// ZeroHash, since that would solve any proof of work puzzle.
 func Hash(value any) string {
-	data, err := json.Marshal(value)
+	data, err := encode(value)
 	if err != nil {
-		return ZeroHash
+		return ""
 	}
 	hash := sha256.Sum256(data)
 	return hexutil.Encode(hash[:])
@@ -73,8 +84,8 @@ func toSignatureValues(sig []byte) (v, r, s *big.Int) {
 // the blkcor stamp embedded into the final hash.
 func stamp(value any) ([]byte, error) {
 
-	// Marshal the data.
-	v, err := json.Marshal(value)
+	// Encode the data.
+	v, err := encode(value)
 	if err != nil {
 		return nil, err
 	}
@@ -90,6 +101,15 @@ func stamp(value any) ([]byte, error) {
 	return data, nil
 }
 
+// encode returns the bytes that represent the value for signing and hashing.
+func encode(value any) ([]byte, error) {
+	if enc, ok := value.(Encoder); ok {
+		return enc.EncodeCanonical()
+	}
+
+	return json.Marshal(value)
+}
+
 // VerifySignature validates the signature of the data.
 func VerifySignature(v *big.Int, r *big.Int, s *big.Int) error {
 	// Check the recovery id is 0 or 1.
@@ -98,8 +118,10 @@ func VerifySignature(v *big.Int, r *big.Int, s *big.Int) error {
 		return errors.New("invalid recovery id")
 	}
 
-	// Check if the signature is valid.
-	if !crypto.ValidateSignatureValues(byte(intV), r, s, false) {
+	// Check if the signature is valid. Signatures with an S value in the
+	// upper half of the curve order are rejected since they are a malleable
+	// copy of the low S signature.
+	if !crypto.ValidateSignatureValues(byte(intV), r, s, true) {
 		return errors.New("invalid signature")
 	}
 	return nil
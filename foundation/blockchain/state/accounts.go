@@ -0,0 +1,15 @@
+package state
+
+import (
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Accounts returns a copy of the database accounts.
+func (s *State) Accounts() map[database.AccountID]database.Account {
+	return s.db.Copy()
+}
+
+// QueryAccount returns a copy of the account from the database.
+func (s *State) QueryAccount(account database.AccountID) (database.Account, error) {
+	return s.db.Query(account)
+}
@@ -0,0 +1,232 @@
+package state
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// ErrNoTransactions is returned when a block is requested to be created
+// and there are not enough transactions.
+var ErrNoTransactions = errors.New("no transactions in mempool")
+
+// =============================================================================
+
+// MineNewBlock attempts to create a new block with a proper hash that can become
+// the next block in the chain.
+func (s *State) MineNewBlock(ctx context.Context) (database.Block, error) {
+	s.evHandler("state: MineNewBlock: MINING: check mempool count")
+
+	// Are there enough transactions in the pool.
+	if s.mempool.Count() == 0 {
+		return database.Block{}, ErrNoTransactions
+	}
+
+	// Pick the best transactions from the mempool.
+	trans := s.mempool.PickBest(uint16(s.genesis.TransPerBlock))
+
+	var block database.Block
+	var err error
+	switch s.consensus {
+	case ConsensusPOA:
+		s.evHandler("state: MineNewBlock: MINING: perform POA")
+
+		// Produce a new block signed by this node's signer.
+		block, err = s.db.POA(s.beneficiaryID, s.db.HashState(), trans, s.signerKey)
+
+	default:
+		s.evHandler("state: MineNewBlock: MINING: perform POW")
+
+		// Attempt to create a new block by solving the POW puzzle. This can be cancelled.
+		block, err = s.db.POW(ctx, s.beneficiaryID, s.db.HashState(), trans)
+	}
+	if err != nil {
+		return database.Block{}, err
+	}
+
+	// Just check one more time we were not cancelled.
+	if ctx.Err() != nil {
+		return database.Block{}, ctx.Err()
+	}
+
+	s.evHandler("state: MineNewBlock: MINING: validate and update database")
+
+	// Validate the block and then update the blockchain database.
+	if err := s.validateUpdateDatabase(block); err != nil {
+		return database.Block{}, err
+	}
+
+	return block, nil
+}
+
+// ProcessProposedBlock takes a block received from a peer, validates it and
+// if that passes, adds the block to the local blockchain.
+func (s *State) ProcessProposedBlock(block database.Block) error {
+	s.evHandler("state: ValidateProposedBlock: started: prevBlk[%s]: newBlk[%s]: numTrans[%d]", block.Header.PrevBlockHash, block.Hash(), len(block.MerkleTree.Values()))
+	defer s.evHandler("state: ValidateProposedBlock: completed: newBlk[%s]", block.Hash())
+
+	// Validate the block and then update the blockchain database.
+	if err := s.validateUpdateDatabase(block); err != nil {
+
+		// A block that doesn't build on our latest block means this node is
+		// on a fork or has fallen behind. The worker will ask the peers for
+		// their status and resolve either case.
+		if errors.Is(err, database.ErrChainForked) || block.Header.Number > s.LatestBlock().Header.Number+1 {
+			s.Worker.SignalPeerSync()
+		}
+
+		return err
+	}
+
+	// If the runMiningOperation function is executing it needs to stop
+	// immediately. The G executing runMiningOperation will not return from the
+	// function until done is called. That allows this function to complete
+	// its state changes before a new mining operation takes place.
+	s.Worker.SignalCancelMining()
+
+	// Any transactions left in the mempool need to be mined on top of the
+	// new block. Under proof of authority this node may be the producer of
+	// the next block.
+	if s.mempool.Count() > 0 {
+		s.Worker.SignalStartMining()
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// validateUpdateDatabase takes the block and validates the block against the
+// consensus rules. If the block passes, then the state of the node is updated
+// including adding the block to disk.
+func (s *State) validateUpdateDatabase(block database.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.applyBlock(block)
+}
+
+// validateConsensus checks the block follows the rules of the consensus
+// protocol the node is running. Under proof of work a block must meet the
+// genesis difficulty and can't carry a producer signature.
+func (s *State) validateConsensus(parent database.Block, block database.Block) error {
+	if s.consensus == ConsensusPOA {
+		return s.validateProducer(parent, block)
+	}
+
+	if block.Header.Difficulty < uint16(s.genesis.Difficulty) {
+		return fmt.Errorf("block difficulty is less than the genesis difficulty, got %d, exp %d", block.Header.Difficulty, s.genesis.Difficulty)
+	}
+
+	if block.Header.R != nil || block.Header.S != nil || block.Header.V != nil {
+		return fmt.Errorf("block %d is signed, proof of work blocks can't be signed", block.Header.Number)
+	}
+
+	return nil
+}
+
+// applyBlock performs the work of validateUpdateDatabase. The caller must
+// hold the state lock.
+func (s *State) applyBlock(block database.Block) error {
+	s.evHandler("state: validateUpdateDatabase: validate block")
+
+	if err := s.validateConsensus(s.db.LatestBlock(), block); err != nil {
+		return err
+	}
+
+	// CORE NOTE: I could add logic to determine if this block was mined by this
+	// node or a peer. If the block is mined by this node, even if a peer beat
+	// me to this function for the same block number, I could replace the peer
+	// block with my own and attempt to have other peers accept my block instead.
+
+	if err := block.ValidateBlock(s.db.LatestBlock(), s.db.HashState(), s.evHandler); err != nil {
+		return err
+	}
+
+	s.evHandler("state: validateUpdateDatabase: validate transactions")
+
+	// Every transaction must be signed by its sender and charge the gas set
+	// by the genesis before any balance is changed.
+	if err := s.db.ValidateTransactions(block); err != nil {
+		return err
+	}
+
+	s.evHandler("state: validateUpdateDatabase: write to disk")
+
+	// Write the new block to the chain on disk.
+	if err := s.db.Write(block); err != nil {
+		return err
+	}
+	s.db.UpdateLatestBlock(block)
+
+	s.evHandler("state: validateUpdateDatabase: update accounts and remove from mempool")
+
+	// Process the transactions and update the accounts.
+	for _, tx := range block.MerkleTree.Values() {
+		s.evHandler("state: validateUpdateDatabase: tx[%s] update and remove", tx)
+
+		// Remove this transaction from the mempool.
+		s.mempool.Delete(tx)
+
+		// Apply the balance changes based on this transaction.
+		if err := s.db.ApplyTransaction(block, tx); err != nil {
+			s.evHandler("state: validateUpdateDatabase: WARNING : %s", err)
+			continue
+		}
+	}
+
+	s.evHandler("state: validateUpdateDatabase: apply mining reward")
+
+	// Apply the mining reward for this block.
+	s.db.ApplyMiningReward(block)
+
+	// Let the viewers know the chain changed, no matter who mined the block.
+	s.evHandler("viewer: validateUpdateDatabase: BLOCK: added: blk[%d]", block.Header.Number)
+
+	return nil
+}
+
+// QueryBlocksByAccount returns the set of blocks that contain a transaction
+// where the specified account is the sender or the receiver. If no account
+// is provided, all the blocks are returned.
+func (s *State) QueryBlocksByAccount(accountID database.AccountID) ([]database.Block, error) {
+	var out []database.Block
+
+	iter := s.db.ForEach()
+	for block, err := iter.Next(); !iter.Done(); block, err = iter.Next() {
+		if err != nil {
+			return nil, err
+		}
+
+		if accountID == "" {
+			out = append(out, block)
+			continue
+		}
+
+		for _, tx := range block.MerkleTree.Values() {
+			if tx.FromID.Normalize() == accountID.Normalize() || tx.ToID.Normalize() == accountID.Normalize() {
+				out = append(out, block)
+				break
+			}
+		}
+	}
+
+	return out, nil
+}
+
+// QueryBlocksByNumber returns the set of blocks based on block numbers. The
+// range is inclusive on both ends.
+func (s *State) QueryBlocksByNumber(from uint64, to uint64) ([]database.Block, error) {
+	out := make([]database.Block, 0, to-from+1)
+	for i := from; i <= to; i++ {
+		block, err := s.db.GetBlock(i)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, block)
+	}
+
+	return out, nil
+}
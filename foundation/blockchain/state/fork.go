@@ -0,0 +1,275 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// forkSearchRange is the number of blocks requested from a peer at a time
+// while searching for the common ancestor of a fork. It matches the number
+// of blocks a node returns in a single call.
+const forkSearchRange = 100
+
+// maxForkLength is the max number of blocks past the common ancestor that
+// are requested from a peer while resolving a fork. A longer peer chain is
+// refused instead of being held in memory.
+const maxForkLength = 1000
+
+// =============================================================================
+
+// NetResolveFork compares the chain of the specified peer with this node's
+// chain. The blocks of the peer are walked backwards until the common
+// ancestor is found. If the peer's chain after the common ancestor has more
+// cumulative work, this node rolls back to the common ancestor and applies
+// the peer's chain. Transactions from the orphaned blocks are returned to
+// the mempool.
+func (s *State) NetResolveFork(pr peer.Peer) error {
+	s.evHandler("state: NetResolveFork: started: %s", pr.Host)
+	defer s.evHandler("state: NetResolveFork: completed: %s", pr.Host)
+
+	ancestor, err := s.netFindCommonAncestor(pr)
+	if err != nil {
+		return err
+	}
+
+	s.evHandler("state: NetResolveFork: common ancestor: blknum[%d]", ancestor)
+
+	theirs, err := s.netRequestChain(pr, ancestor+1)
+	if err != nil {
+		return err
+	}
+
+	if len(theirs) == 0 {
+		s.evHandler("state: NetResolveFork: peer has no blocks past the common ancestor")
+		return nil
+	}
+
+	// Check the peer's chain is well formed and every transaction on it was
+	// signed by its sender before this node's chain is touched. The state
+	// root can only be checked once the blocks are applied, so each block's
+	// own state root is used here.
+	parent, err := s.blockByNumber(ancestor)
+	if err != nil {
+		return err
+	}
+	for _, block := range theirs {
+		if err := s.validateConsensus(parent, block); err != nil {
+			return fmt.Errorf("peer chain invalid: %w", err)
+		}
+		if err := block.ValidateBlock(parent, block.Header.StateRoot, s.evHandler); err != nil {
+			return fmt.Errorf("peer chain invalid: %w", err)
+		}
+		if err := s.db.ValidateTransactions(block); err != nil {
+			return fmt.Errorf("peer chain invalid: %w", err)
+		}
+		parent = block
+	}
+
+	latest := s.LatestBlock().Header.Number
+	if latest < ancestor {
+		return fmt.Errorf("chain changed while resolving fork: latest-blknum[%d]: ancestor[%d]", latest, ancestor)
+	}
+
+	var ours []database.Block
+	if latest > ancestor {
+		ours, err = s.QueryBlocksByNumber(ancestor+1, latest)
+		if err != nil {
+			return err
+		}
+	}
+
+	ourWork := s.chainWork(ours)
+	theirWork := s.chainWork(theirs)
+	if theirWork.Cmp(ourWork) <= 0 {
+		s.evHandler("state: NetResolveFork: keeping our chain: our-work[%s]: their-work[%s]", ourWork, theirWork)
+		return nil
+	}
+
+	s.evHandler("state: NetResolveFork: switching chains: our-work[%s]: their-work[%s]", ourWork, theirWork)
+
+	if err := s.reorganize(ancestor, ours, theirs); err != nil {
+		return err
+	}
+
+	// Any mining in progress is on top of an orphaned block. Mine any
+	// transactions that went back into the mempool.
+	s.Worker.SignalCancelMining()
+	if s.mempool.Count() > 0 {
+		s.Worker.SignalStartMining()
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// reorganize rolls the database back to the common ancestor and applies the
+// specified chain. If the new chain fails to apply, the orphaned chain is
+// put back. The transactions of the orphaned blocks that were not mined by
+// the new chain are returned to the mempool.
+func (s *State) reorganize(ancestor uint64, ours []database.Block, theirs []database.Block) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Make sure a block wasn't added since the chains were compared.
+	expTip := ancestor
+	if len(ours) > 0 {
+		expTip = ours[len(ours)-1].Header.Number
+	}
+	if latest := s.db.LatestBlock().Header.Number; latest != expTip {
+		return fmt.Errorf("chain changed while resolving fork: latest-blknum[%d]: exp[%d]", latest, expTip)
+	}
+
+	s.evHandler("state: reorganize: rollback: blknum[%d]: orphaned[%d]", ancestor, len(ours))
+
+	if err := s.db.Rollback(ancestor); err != nil {
+		return err
+	}
+
+	for _, block := range theirs {
+		if err := s.applyBlock(block); err != nil {
+			s.evHandler("state: reorganize: blk[%d]: ERROR: %s: restoring our chain", block.Header.Number, err)
+
+			if err := s.restoreChain(ancestor, ours); err != nil {
+				return fmt.Errorf("restoring chain after failed reorganization: %w", err)
+			}
+
+			return fmt.Errorf("applying peer chain: %w", err)
+		}
+	}
+
+	// Return the orphaned transactions to the mempool. A transaction with a
+	// nonce that has been used by the new chain can never be mined.
+	for _, block := range ours {
+		for _, tx := range block.MerkleTree.Values() {
+			account, err := s.db.Query(tx.FromID)
+			if err != nil || tx.Nonce <= account.Nonce {
+				continue
+			}
+
+			if err := s.mempool.Upsert(tx); err != nil {
+				s.evHandler("state: reorganize: tx[%s]: WARNING: %s", tx, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreChain rolls the database back to the common ancestor and applies
+// the specified blocks again. The caller must hold the state lock.
+func (s *State) restoreChain(ancestor uint64, blocks []database.Block) error {
+	if err := s.db.Rollback(ancestor); err != nil {
+		return err
+	}
+
+	for _, block := range blocks {
+		if err := s.applyBlock(block); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// netFindCommonAncestor walks backwards through the peer's blocks, a range
+// at a time, until a block with the same hash as our block is found. A
+// result of 0 means the chains only share the genesis.
+func (s *State) netFindCommonAncestor(pr peer.Peer) (uint64, error) {
+	hi := s.LatestBlock().Header.Number
+
+	for hi > 0 {
+		lo := uint64(1)
+		if hi > forkSearchRange {
+			lo = hi - forkSearchRange + 1
+		}
+
+		theirs, err := s.netRequestBlocks(pr, lo, strconv.FormatUint(hi, 10))
+		if err != nil {
+			return 0, err
+		}
+
+		for i := len(theirs) - 1; i >= 0; i-- {
+			ours, err := s.db.GetBlock(theirs[i].Header.Number)
+			if err != nil {
+				return 0, err
+			}
+
+			if ours.Hash() == theirs[i].Hash() {
+				return ours.Header.Number, nil
+			}
+		}
+
+		hi = lo - 1
+	}
+
+	return 0, nil
+}
+
+// netRequestChain requests every block the peer has starting with the
+// specified block number up to the latest block the peer reports. The peer
+// must return the blocks in order without gaps, and the chain can't be longer
+// than maxForkLength.
+func (s *State) netRequestChain(pr peer.Peer, from uint64) ([]database.Block, error) {
+	ps, err := s.NetRequestPeerStatus(pr)
+	if err != nil {
+		return nil, err
+	}
+
+	last := ps.LatestBlockNumber
+	if last < from {
+		return nil, nil
+	}
+
+	if n := last - from + 1; n > maxForkLength {
+		return nil, fmt.Errorf("peer chain is too long past the common ancestor, got %d blocks, max %d", n, maxForkLength)
+	}
+
+	var chain []database.Block
+	for from <= last {
+		blocks, err := s.netRequestBlocks(pr, from, strconv.FormatUint(last, 10))
+		if err != nil {
+			return nil, err
+		}
+
+		if len(blocks) == 0 {
+			return nil, fmt.Errorf("peer returned no blocks from %d, reported latest %d", from, last)
+		}
+
+		for _, block := range blocks {
+			if block.Header.Number != from || from > last {
+				return nil, fmt.Errorf("peer returned block %d out of order, exp %d, reported latest %d", block.Header.Number, from, last)
+			}
+
+			chain = append(chain, block)
+			from++
+		}
+	}
+
+	return chain, nil
+}
+
+// blockByNumber returns the specified block. Block 0 represents the genesis
+// and is returned as the zero value block.
+func (s *State) blockByNumber(num uint64) (database.Block, error) {
+	if num == 0 {
+		return database.Block{}, nil
+	}
+
+	return s.db.GetBlock(num)
+}
+
+// chainWork calculates the cumulative work of the specified blocks using
+// the weight of each block under the consensus protocol.
+func (s *State) chainWork(blocks []database.Block) *big.Int {
+	work := new(big.Int)
+	for _, block := range blocks {
+		work.Add(work, s.blockWeight(block))
+	}
+
+	return work
+}
@@ -0,0 +1,123 @@
+package state_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+func TestNetResolveFork(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	// This node mined its own first block. The peer mined a different first
+	// block and a second block on top of it, so the peer's chain has more
+	// work.
+	ours := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, minerID, 10)})
+	theirs1 := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, victimID, 100)})
+
+	tt := []struct {
+		name   string
+		tx     func(t *testing.T) database.BlockTx
+		latest string
+		err    error
+	}{
+		{
+			name:   "valid",
+			tx:     func(t *testing.T) database.BlockTx { return blockTx(t, pk, chainID, 2, victimID, 100) },
+			latest: "theirs",
+		},
+		{
+			name:   "forged signature",
+			tx:     func(t *testing.T) database.BlockTx { return forgedTx(t, 2, senderID, victimID, 815) },
+			latest: "ours",
+			err:    database.ErrInvalidTransaction,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			theirs2 := mineBlock(t, gen, theirs1, []database.BlockTx{tst.tx(t)}, theirs1)
+			pr := newPeer(t, gen, []database.Block{theirs1, theirs2})
+
+			st := newState(t, gen, memory.New())
+			if err := st.ProcessProposedBlock(ours); err != nil {
+				t.Fatalf("Should be able to process our block: %s", err)
+			}
+
+			err := st.NetResolveFork(pr)
+
+			switch {
+			case tst.err == nil && err != nil:
+				t.Fatalf("Should be able to resolve the fork: %s", err)
+			case tst.err != nil && !errors.Is(err, tst.err):
+				t.Fatalf("Should get the expected error, got %v, exp %v", err, tst.err)
+			}
+
+			exp := ours
+			if tst.latest == "theirs" {
+				exp = theirs2
+			}
+			if hash := st.LatestBlock().Hash(); hash != exp.Hash() {
+				t.Fatalf("Should be on the %s chain, got %s, exp %s", tst.latest, hash, exp.Hash())
+			}
+
+			if tst.err != nil {
+				checkBalance(t, st, senderID, balance-10-gasPrice)
+				if _, err := st.QueryAccount(victimID); !errors.Is(err, database.ErrUnknownAccount) {
+					t.Fatalf("Should not credit the victim account, got %v", err)
+				}
+				return
+			}
+
+			checkBalance(t, st, victimID, 200)
+		})
+	}
+}
+
+func TestNetResolveForkHostilePeer(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	ours := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, minerID, 10)})
+	theirs1 := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, victimID, 100)})
+	theirs2 := mineBlock(t, gen, theirs1, []database.BlockTx{blockTx(t, pk, chainID, 2, victimID, 100)}, theirs1)
+
+	tt := []struct {
+		name   string
+		latest uint64
+		blocks []database.Block
+	}{
+		{name: "blocks don't advance", latest: 2, blocks: []database.Block{theirs1}},
+		{name: "skips a block", latest: 2, blocks: []database.Block{theirs2}},
+		{name: "more blocks than reported", latest: 1, blocks: []database.Block{theirs1, theirs2}},
+		{name: "chain too long", latest: 1_000_000, blocks: []database.Block{theirs1, theirs2}},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			// The peer ignores the requested range and always returns the
+			// same blocks.
+			pr := startPeer(t, gen, tst.latest, func(from uint64, last uint64) []database.Block {
+				return tst.blocks
+			})
+
+			st := newState(t, gen, memory.New())
+			if err := st.ProcessProposedBlock(ours); err != nil {
+				t.Fatalf("Should be able to process our block: %s", err)
+			}
+
+			if err := st.NetResolveFork(pr); err == nil {
+				t.Fatalf("Should not be able to resolve the fork")
+			}
+
+			if hash := st.LatestBlock().Hash(); hash != ours.Hash() {
+				t.Fatalf("Should be on our chain, got %s, exp %s", hash, ours.Hash())
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package state
+
+import (
+	"fmt"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// QueryMempool returns a copy of the mempool in the order the transactions
+// would be selected for the next block.
+func (s *State) QueryMempool() []database.BlockTx {
+	return s.mempool.PickBest()
+}
+
+// MempoolLength returns the current length of the mempool.
+func (s *State) MempoolLength() int {
+	return s.mempool.Count()
+}
+
+// UpsertWalletTransaction accepts a transaction from a wallet for inclusion.
+func (s *State) UpsertWalletTransaction(signedTx database.SignedTx) error {
+
+	// CORE NOTE: It's up to the wallet to make sure the account has a proper
+	// balance and this transaction has a proper nonce. Fees will be taken if
+	// this transaction is mined into a block it doesn't have enough money to
+	// pay or the nonce isn't the next expected nonce for the account.
+
+	// Check the signed transaction has a proper signature, the from matches the
+	// signature, and the from and to fields are properly formatted.
+	if err := signedTx.Validate(uint16(s.genesis.ChainID)); err != nil {
+		return err
+	}
+
+	// The mempool is keyed by account, so the same account must always be
+	// written the same way. The case doesn't change the signed payload.
+	signedTx.FromID = signedTx.FromID.Normalize()
+	signedTx.ToID = signedTx.ToID.Normalize()
+
+	// Reject transactions with a nonce that has already been used.
+	account, err := s.db.Query(signedTx.FromID)
+	if err != nil {
+		return err
+	}
+	if signedTx.Nonce <= account.Nonce {
+		return fmt.Errorf("%w: got %d, last used %d", database.ErrInvalidNonce, signedTx.Nonce, account.Nonce)
+	}
+
+	tx := database.NewBlockTx(signedTx, uint64(s.genesis.GasPrice), database.GasUnitsPerTx)
+	if err := s.mempool.Upsert(tx); err != nil {
+		return err
+	}
+
+	s.Worker.SignalShareTx(tx)
+	s.Worker.SignalStartMining()
+
+	return nil
+}
+
+// UpsertNodeTransaction accepts a transaction from a node for inclusion.
+func (s *State) UpsertNodeTransaction(tx database.BlockTx) error {
+
+	// Check the signed transaction has a proper signature, the from matches the
+	// signature, the from and to fields are properly formatted and the peer
+	// didn't change the gas the transaction is charged.
+	if err := s.db.ValidateBlockTx(tx); err != nil {
+		return err
+	}
+
+	tx.FromID = tx.FromID.Normalize()
+	tx.ToID = tx.ToID.Normalize()
+
+	if err := s.mempool.Upsert(tx); err != nil {
+		return err
+	}
+
+	s.Worker.SignalStartMining()
+
+	return nil
+}
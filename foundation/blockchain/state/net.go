@@ -0,0 +1,218 @@
+package state
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// baseURL represents the base URL for the private node API.
+const baseURL = "http://%s/v1/node"
+
+// client is used for all node to node communication.
+var client = http.Client{
+	Timeout: 10 * time.Second,
+}
+
+// =============================================================================
+
+// NetSendNodeAvailableToPeers shares this node is available to
+// participate in the network with the known peers.
+func (s *State) NetSendNodeAvailableToPeers() {
+	s.evHandler("state: NetSendNodeAvailableToPeers: started")
+	defer s.evHandler("state: NetSendNodeAvailableToPeers: completed")
+
+	host := peer.New(s.Host())
+
+	for _, pr := range s.KnownExternalPeers() {
+		s.evHandler("state: NetSendNodeAvailableToPeers: send: host[%s] to peer[%s]", host.Host, pr.Host)
+
+		url := fmt.Sprintf("%s/peers", fmt.Sprintf(baseURL, pr.Host))
+		if err := s.send(http.MethodPost, url, host, nil); err != nil {
+			s.evHandler("state: NetSendNodeAvailableToPeers: WARNING: %s", err)
+		}
+	}
+}
+
+// NetRequestPeerStatus looks for new nodes on the blockchain by asking
+// known nodes for their peer list. New nodes are added to the list.
+func (s *State) NetRequestPeerStatus(pr peer.Peer) (peer.PeerStatus, error) {
+	s.evHandler("state: NetRequestPeerStatus: started: %s", pr.Host)
+	defer s.evHandler("state: NetRequestPeerStatus: completed: %s", pr.Host)
+
+	url := fmt.Sprintf("%s/status", fmt.Sprintf(baseURL, pr.Host))
+
+	var ps peer.PeerStatus
+	if err := s.send(http.MethodGet, url, nil, &ps); err != nil {
+		return peer.PeerStatus{}, err
+	}
+
+	s.evHandler("state: NetRequestPeerStatus: peer-node[%s]: latest-blknum[%d]: peer-list[%s]", pr.Host, ps.LatestBlockNumber, ps.KnownPeers)
+
+	return ps, nil
+}
+
+// NetRequestPeerBlocks queries the specified node asking for blocks this
+// node does not have. The blocks are requested in ranges and every block is
+// validated against its parent and applied through the same path as a
+// proposed block.
+func (s *State) NetRequestPeerBlocks(pr peer.Peer) error {
+	s.evHandler("state: NetRequestPeerBlocks: started: %s", pr.Host)
+	defer s.evHandler("state: NetRequestPeerBlocks: completed: %s", pr.Host)
+
+	for {
+		from := s.LatestBlock().Header.Number + 1
+
+		blocks, err := s.netRequestBlocks(pr, from, "latest")
+		if err != nil {
+			return err
+		}
+
+		// The peer has no more blocks past our latest block.
+		if len(blocks) == 0 {
+			return nil
+		}
+
+		s.evHandler("state: NetRequestPeerBlocks: received: from-blknum[%d]: blocks[%d]", from, len(blocks))
+
+		for _, block := range blocks {
+			if err := s.validateUpdateDatabase(block); err != nil {
+				return err
+			}
+		}
+
+		// Any mining in progress is on top of a stale block.
+		s.Worker.SignalCancelMining()
+	}
+}
+
+// NetSendBlockToPeers takes the new mined block and sends it to all know peers.
+func (s *State) NetSendBlockToPeers(block database.Block) error {
+	s.evHandler("state: NetSendBlockToPeers: started")
+	defer s.evHandler("state: NetSendBlockToPeers: completed")
+
+	// Every peer is sent the block even if a previous peer failed. The first
+	// failure is returned to the caller.
+	var firstErr error
+	for _, pr := range s.KnownExternalPeers() {
+		url := fmt.Sprintf("%s/block/propose", fmt.Sprintf(baseURL, pr.Host))
+
+		if err := s.send(http.MethodPost, url, database.NewBlockData(block), nil); err != nil {
+			s.evHandler("state: NetSendBlockToPeers: WARNING: %s: %s", pr.Host, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", pr.Host, err)
+			}
+		}
+	}
+
+	return firstErr
+}
+
+// NetSendTxToPeers shares a new block transaction with the known peers.
+func (s *State) NetSendTxToPeers(tx database.BlockTx) {
+	s.evHandler("state: NetSendTxToPeers: started")
+	defer s.evHandler("state: NetSendTxToPeers: completed")
+
+	// CORE NOTE: Bitcoin does not send the full transaction immediately to save
+	// on bandwidth. A node will send the transaction's mempool key first so the
+	// receiving node can check if they already have the transaction or not. If
+	// the receiving node doesn't have it, then it will request the transaction
+	// based on the mempool key it received.
+
+	for _, pr := range s.KnownExternalPeers() {
+		url := fmt.Sprintf("%s/tx/submit", fmt.Sprintf(baseURL, pr.Host))
+		if err := s.send(http.MethodPost, url, tx, nil); err != nil {
+			s.evHandler("state: NetSendTxToPeers: WARNING: %s", err)
+		}
+	}
+}
+
+// =============================================================================
+
+// netRequestBlocks asks the peer for the specified range of blocks. The to
+// value can be "latest". The peer may return fewer blocks than requested.
+func (s *State) netRequestBlocks(pr peer.Peer, from uint64, to string) ([]database.Block, error) {
+	url := fmt.Sprintf("%s/block/list/%d/%s", fmt.Sprintf(baseURL, pr.Host), from, to)
+
+	var blocksData []database.BlockData
+	if err := s.send(http.MethodGet, url, nil, &blocksData); err != nil {
+		return nil, err
+	}
+
+	blocks := make([]database.Block, len(blocksData))
+	for i, blockData := range blocksData {
+		block, err := database.ToBlock(blockData)
+		if err != nil {
+			return nil, err
+		}
+
+		if hash := block.Hash(); hash != blockData.Hash {
+			return nil, fmt.Errorf("block %d hash doesn't match, got %s, exp %s", block.Header.Number, blockData.Hash, hash)
+		}
+
+		blocks[i] = block
+	}
+
+	return blocks, nil
+}
+
+// send is a helper function to send an HTTP request to a node. Every request
+// carries the hash of this node's genesis so peers on a different chain can
+// refuse it.
+func (s *State) send(method string, url string, dataSend any, dataRecv any) error {
+	var req *http.Request
+
+	switch {
+	case dataSend != nil:
+		data, err := json.Marshal(dataSend)
+		if err != nil {
+			return err
+		}
+		req, err = http.NewRequest(method, url, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+
+	default:
+		var err error
+		req, err = http.NewRequest(method, url, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(peer.GenesisHashHeader, s.genesisHash)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusAccepted {
+		msg, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("%s: %s", resp.Status, string(msg))
+	}
+
+	if dataRecv != nil {
+		if err := json.NewDecoder(resp.Body).Decode(dataRecv); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,126 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// CORE NOTE: Under proof of authority the genesis lists the accounts that are
+// authorized to produce blocks. The signers take turns in the order listed,
+// with the in-turn producer of any block chosen by the block number. So the
+// chain doesn't stall when the in-turn signer is offline, the next signers in
+// the list are allowed to produce the block out of turn, each waiting one
+// more producerTimeout after the parent block. Out-of-turn blocks carry less
+// weight when forks are resolved, so the chain of the in-turn signers wins.
+
+// producerTimeout is how long each signer waits for the signers before it in
+// the turn order to produce a block.
+const producerTimeout = 5 * time.Second
+
+// maxClockDrift is how far in the future a block's timestamp can be. This
+// keeps an out-of-turn signer from skipping its wait by moving its clock.
+const maxClockDrift = 2 * time.Second
+
+// The weight of a block under proof of authority.
+const (
+	inTurnWeight    = 2
+	outOfTurnWeight = 1
+)
+
+// ProducerDelay returns how long this node needs to wait before it's allowed
+// to produce the next block. The second return value is false when this node
+// can't produce blocks at all. Under proof of work any node can produce the
+// next block right away.
+func (s *State) ProducerDelay() (time.Duration, bool) {
+	if s.consensus != ConsensusPOA {
+		return 0, true
+	}
+
+	latest := s.LatestBlock()
+
+	turn, ok := s.signerTurn(latest.Header.Number+1, s.beneficiaryID)
+	if !ok {
+		return 0, false
+	}
+
+	delay := time.Until(producerTime(latest, turn))
+	if delay < 0 {
+		delay = 0
+	}
+
+	return delay, true
+}
+
+// signerTurn returns how many turns the signer is away from being the
+// in-turn producer of the specified block. The second return value is false
+// when the account is not a signer.
+func (s *State) signerTurn(blockNumber uint64, signer database.AccountID) (uint64, bool) {
+	n := uint64(len(s.signers))
+	inTurn := blockNumber % n
+
+	for i, account := range s.signers {
+		if strings.EqualFold(string(account), string(signer)) {
+			return (uint64(i) + n - inTurn) % n, true
+		}
+	}
+
+	return 0, false
+}
+
+// producerTime returns the earliest time a signer the specified number of
+// turns away from the in-turn producer can produce the block after the parent.
+func producerTime(parent database.Block, turn uint64) time.Time {
+	parentTime := time.UnixMilli(int64(parent.Header.TimeStamp))
+	return parentTime.Add(time.Duration(turn) * producerTimeout)
+}
+
+// validateProducer checks the block was signed by a signer and the signer
+// waited for its turn to produce the block.
+func (s *State) validateProducer(parent database.Block, block database.Block) error {
+	signer, err := block.Signer()
+	if err != nil {
+		return fmt.Errorf("block %d: %w", block.Header.Number, err)
+	}
+
+	turn, ok := s.signerTurn(block.Header.Number, signer)
+	if !ok {
+		return fmt.Errorf("block %d signed by %s which is not a signer", block.Header.Number, signer)
+	}
+
+	blockTime := time.UnixMilli(int64(block.Header.TimeStamp))
+	if limit := time.Now().Add(maxClockDrift); blockTime.After(limit) {
+		return fmt.Errorf("block %d timestamp is in the future, block %v, limit %v", block.Header.Number, blockTime, limit)
+	}
+
+	if exp := producerTime(parent, turn); blockTime.Before(exp) {
+		return fmt.Errorf("block %d signed by %s before its turn, block %v, exp %v", block.Header.Number, signer, blockTime, exp)
+	}
+
+	return nil
+}
+
+// blockWeight returns the weight of the block when comparing chains. Under
+// proof of work the weight is the expected number of hashes needed to solve
+// the block, 16 to the power of the block difficulty. Under proof of
+// authority blocks produced in turn weigh more than blocks produced out of
+// turn.
+func (s *State) blockWeight(block database.Block) *big.Int {
+	if s.consensus != ConsensusPOA {
+		return new(big.Int).Lsh(big.NewInt(1), 4*uint(block.Header.Difficulty))
+	}
+
+	signer, err := block.Signer()
+	if err != nil {
+		return big.NewInt(0)
+	}
+
+	if turn, ok := s.signerTurn(block.Header.Number, signer); ok && turn == 0 {
+		return big.NewInt(inTurnWeight)
+	}
+
+	return big.NewInt(outOfTurnWeight)
+}
@@ -0,0 +1,182 @@
+package state_test
+
+import (
+	"crypto/ecdsa"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+func TestProcessProposedBlockPOA(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	signers := newSigners(t, 3)
+	gen := poaGenesis(t, senderID, signers)
+
+	// The signer at index 1 is in turn for block 1 and the signer at
+	// index 2 is in turn for block 2.
+	inTurn1 := produceBlock(t, gen, signers[1], database.Block{}, 1)
+
+	tt := []struct {
+		name  string
+		chain []database.Block
+		block func(t *testing.T) database.Block
+		valid bool
+	}{
+		{
+			name:  "in turn",
+			block: func(t *testing.T) database.Block { return inTurn1 },
+			valid: true,
+		},
+		{
+			name: "out of turn after the timeout",
+			block: func(t *testing.T) database.Block {
+				return produceBlock(t, gen, signers[0], database.Block{}, 1)
+			},
+			valid: true,
+		},
+		{
+			name:  "out of turn before the timeout",
+			chain: []database.Block{inTurn1},
+			block: func(t *testing.T) database.Block {
+				return produceBlock(t, gen, signers[0], inTurn1, 2, inTurn1)
+			},
+		},
+		{
+			name: "not a signer",
+			block: func(t *testing.T) database.Block {
+				return produceBlock(t, gen, pk, database.Block{}, 1)
+			},
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			st := newPOAState(t, gen, signers[2])
+			for _, block := range tst.chain {
+				if err := st.ProcessProposedBlock(block); err != nil {
+					t.Fatalf("Should be able to process block %d: %s", block.Header.Number, err)
+				}
+			}
+
+			block := tst.block(t)
+			err := st.ProcessProposedBlock(block)
+
+			switch {
+			case tst.valid && err != nil:
+				t.Fatalf("Should be able to process the block: %s", err)
+			case !tst.valid && err == nil:
+				t.Fatalf("Should not be able to process the block")
+			}
+		})
+	}
+}
+
+func TestNetResolveForkPOA(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	signers := newSigners(t, 3)
+	gen := poaGenesis(t, senderID, signers)
+
+	// Both blocks extend the genesis, but only the first was produced by the
+	// signer in turn.
+	inTurn := produceBlock(t, gen, signers[1], database.Block{}, 1)
+	outOfTurn := produceBlock(t, gen, signers[0], database.Block{}, 1)
+
+	tt := []struct {
+		name   string
+		ours   database.Block
+		theirs database.Block
+		exp    database.Block
+	}{
+		{name: "switch to in turn", ours: outOfTurn, theirs: inTurn, exp: inTurn},
+		{name: "keep in turn", ours: inTurn, theirs: outOfTurn, exp: inTurn},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			st := newPOAState(t, gen, signers[2])
+			if err := st.ProcessProposedBlock(tst.ours); err != nil {
+				t.Fatalf("Should be able to process our block: %s", err)
+			}
+
+			pr := newPeer(t, gen, []database.Block{tst.theirs})
+			if err := st.NetResolveFork(pr); err != nil {
+				t.Fatalf("Should be able to resolve the fork: %s", err)
+			}
+
+			if hash := st.LatestBlock().Hash(); hash != tst.exp.Hash() {
+				t.Fatalf("Should be on the expected chain, got %s, exp %s", hash, tst.exp.Hash())
+			}
+		})
+	}
+}
+
+// =============================================================================
+
+func newSigners(t *testing.T, n int) []*ecdsa.PrivateKey {
+	signers := make([]*ecdsa.PrivateKey, n)
+	for i := range signers {
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Should be able to generate a signer key: %s", err)
+		}
+		signers[i] = pk
+	}
+
+	return signers
+}
+
+// poaGenesis adds the signers to the test genesis. The difficulty is kept
+// since the same genesis must also be usable under proof of work.
+func poaGenesis(t *testing.T, senderID database.AccountID, signers []*ecdsa.PrivateKey) genesis.Genesis {
+	gen := testGenesis(senderID)
+	for _, pk := range signers {
+		gen.Signers = append(gen.Signers, string(database.PublicKeyToAccountID(pk.PublicKey)))
+	}
+
+	if err := gen.Validate(); err != nil {
+		t.Fatalf("Should be able to validate the genesis: %s", err)
+	}
+
+	return gen
+}
+
+func newPOAState(t *testing.T, gen genesis.Genesis, signerKey *ecdsa.PrivateKey) *state.State {
+	st, err := state.New(state.Config{
+		BeneficiaryID:  database.PublicKeyToAccountID(signerKey.PublicKey),
+		SignerKey:      signerKey,
+		Host:           "0.0.0.0:9080",
+		Storage:        memory.New(),
+		Genesis:        gen,
+		SelectStrategy: "tip",
+		Consensus:      state.ConsensusPOA,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct the state: %s", err)
+	}
+
+	return st
+}
+
+// produceBlock produces a block signed by the signer on top of the parent.
+// The block holds a transaction from the sender with the specified nonce.
+func produceBlock(t *testing.T, gen genesis.Genesis, signerKey *ecdsa.PrivateKey, parent database.Block, nonce uint64, chain ...database.Block) database.Block {
+	block, err := database.POA(database.POAArgs{
+		BeneficiaryID: database.PublicKeyToAccountID(signerKey.PublicKey),
+		MiningReward:  uint64(gen.MiningReward),
+		PrevBlock:     parent,
+		StateRoot:     stateRoot(t, gen, chain...),
+		Trans:         []database.BlockTx{blockTx(t, mustKey(t, senderKey), chainID, nonce, victimID, 100)},
+		SignerKey:     signerKey,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to produce the block: %s", err)
+	}
+
+	return block
+}
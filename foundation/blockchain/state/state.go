@@ -0,0 +1,248 @@
+// Package state is the core API for the blockchain and implements all the
+// business rules and processing.
+package state
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/mempool"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// The set of different consensus protocols that can be used.
+const (
+	ConsensusPOW = "POW"
+	ConsensusPOA = "POA"
+)
+
+// =============================================================================
+
+// EventHandler defines a function that is called when events
+// occur in the processing of persisting blocks.
+type EventHandler func(v string, args ...any)
+
+// Worker interface represents the behavior required to be implemented by any
+// package providing support for mining, peer updates, and transaction sharing.
+type Worker interface {
+	Shutdown()
+	SignalStartMining()
+	SignalCancelMining()
+	SignalShareTx(blockTx database.BlockTx)
+	SignalPeerSync()
+}
+
+// =============================================================================
+
+// Config represents the configuration required to start
+// the blockchain node.
+type Config struct {
+	BeneficiaryID  database.AccountID
+	SignerKey      *ecdsa.PrivateKey
+	Host           string
+	KnownPeers     *peer.PeerSet
+	Storage        database.Storage
+	Genesis        genesis.Genesis
+	SelectStrategy string
+	Consensus      string
+	EvHandler      EventHandler
+}
+
+// State manages the blockchain database.
+type State struct {
+	mu sync.RWMutex
+
+	beneficiaryID database.AccountID
+	signerKey     *ecdsa.PrivateKey
+	signers       []database.AccountID
+	host          string
+	knownPeers    *peer.PeerSet
+	consensus     string
+	evHandler     EventHandler
+
+	genesis     genesis.Genesis
+	genesisHash string
+	mempool     *mempool.Mempool
+	db          *database.Database
+
+	Worker Worker
+}
+
+// New constructs a new blockchain for data management.
+func New(cfg Config) (*State, error) {
+
+	// A beneficiary is required to receive the rewards for mining.
+	if !cfg.BeneficiaryID.IsAccountID() {
+		return nil, fmt.Errorf("invalid beneficiary account %q", cfg.BeneficiaryID)
+	}
+
+	// Validate the consensus protocol the node will run. Proof of authority
+	// requires the set of signers from the genesis and the key this node
+	// signs blocks with.
+	var signers []database.AccountID
+	switch cfg.Consensus {
+	case ConsensusPOW:
+	case ConsensusPOA:
+		if len(cfg.Genesis.Signers) == 0 {
+			return nil, errors.New("proof of authority requires signers in the genesis")
+		}
+		for _, signer := range cfg.Genesis.Signers {
+			signers = append(signers, database.AccountID(common.HexToAddress(signer).Hex()))
+		}
+
+		if cfg.SignerKey == nil {
+			return nil, errors.New("proof of authority requires a signer key")
+		}
+		if !strings.EqualFold(string(database.PublicKeyToAccountID(cfg.SignerKey.PublicKey)), string(cfg.BeneficiaryID)) {
+			return nil, errors.New("signer key doesn't belong to the beneficiary account")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported consensus protocol %q", cfg.Consensus)
+	}
+
+	// A node can start without knowing any peers.
+	if cfg.KnownPeers == nil {
+		cfg.KnownPeers = peer.NewPeerSet()
+	}
+
+	// Build a safe event handler function for use.
+	ev := func(v string, args ...any) {
+		if cfg.EvHandler != nil {
+			cfg.EvHandler(v, args...)
+		}
+	}
+
+	// Access the storage for the blockchain.
+	db, err := database.New(cfg.Genesis, cfg.Storage, ev)
+	if err != nil {
+		return nil, err
+	}
+
+	// Construct a mempool with the specified sort strategy.
+	mempool, err := mempool.NewWithStrategy(cfg.SelectStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the State to provide support for managing the blockchain.
+	state := State{
+		beneficiaryID: cfg.BeneficiaryID,
+		signerKey:     cfg.SignerKey,
+		signers:       signers,
+		host:          cfg.Host,
+		knownPeers:    cfg.KnownPeers,
+		consensus:     cfg.Consensus,
+		evHandler:     ev,
+
+		genesis:     cfg.Genesis,
+		genesisHash: cfg.Genesis.Hash(),
+		mempool:     mempool,
+		db:          db,
+
+		// The call to worker.Run will replace this with the real worker and
+		// start everything up and running for the node.
+		Worker: noopWorker{},
+	}
+
+	return &state, nil
+}
+
+// Shutdown cleanly brings the node down.
+func (s *State) Shutdown() error {
+	s.evHandler("state: shutdown: started")
+	defer s.evHandler("state: shutdown: completed")
+
+	// Make sure the database file is properly closed.
+	defer func() {
+		s.db.Close()
+	}()
+
+	// Stop all blockchain writing activity.
+	s.Worker.Shutdown()
+
+	return nil
+}
+
+// Consensus returns the consensus protocol the node is running.
+func (s *State) Consensus() string {
+	return s.consensus
+}
+
+// Genesis returns a copy of the genesis information.
+func (s *State) Genesis() genesis.Genesis {
+	return s.genesis
+}
+
+// Host returns a copy of host information.
+func (s *State) Host() string {
+	return s.host
+}
+
+// KnownExternalPeers retrieves a copy of the known peer list without
+// including this node.
+func (s *State) KnownExternalPeers() []peer.Peer {
+	return s.knownPeers.Copy(s.host)
+}
+
+// KnownPeers retrieves a copy of the full known peer list which includes
+// this node as well.
+func (s *State) KnownPeers() []peer.Peer {
+	return s.knownPeers.Copy("")
+}
+
+// AddKnownPeer provides the ability to add a new peer to the known peer list.
+// It reports true when the peer was not already known.
+func (s *State) AddKnownPeer(pr peer.Peer) bool {
+	if pr.Match(s.host) {
+		return false
+	}
+
+	return s.knownPeers.Add(pr)
+}
+
+// RemoveKnownPeer provides the ability to remove a peer from
+// the known peer list.
+func (s *State) RemoveKnownPeer(pr peer.Peer) {
+	s.knownPeers.Remove(pr)
+}
+
+// RecordPeerSuccess clears any failures recorded against the peer.
+func (s *State) RecordPeerSuccess(pr peer.Peer) {
+	s.knownPeers.Succeeded(pr)
+}
+
+// RecordPeerFailure records a failed request to the peer. It reports true
+// when the peer failed too many times in a row and was dropped from the
+// known peer list.
+func (s *State) RecordPeerFailure(pr peer.Peer) bool {
+	return s.knownPeers.Failed(pr)
+}
+
+// GenesisHash returns the hash of the genesis information. Nodes only
+// peer with other nodes running the same genesis.
+func (s *State) GenesisHash() string {
+	return s.genesisHash
+}
+
+// LatestBlock returns a copy the current latest block.
+func (s *State) LatestBlock() database.Block {
+	return s.db.LatestBlock()
+}
+
+// =============================================================================
+
+// noopWorker is used until a real worker is assigned to the state so the
+// state can be used on its own in tests.
+type noopWorker struct{}
+
+func (noopWorker) Shutdown()                         {}
+func (noopWorker) SignalStartMining()                {}
+func (noopWorker) SignalCancelMining()               {}
+func (noopWorker) SignalShareTx(tx database.BlockTx) {}
+func (noopWorker) SignalPeerSync()                   {}
@@ -0,0 +1,377 @@
+package state_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	senderKey = "9f332e3700d8fc2446eaf6d15034cf96e0c2745e40353deef032a5dbf1dfed93"
+	victimID  = database.AccountID("0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4")
+	minerID   = database.AccountID("0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8")
+	chainID   = uint16(1)
+	balance   = uint64(1000)
+	gasPrice  = uint64(15)
+)
+
+func TestProcessProposedBlock(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+
+	tt := []struct {
+		name string
+		tx   func(t *testing.T) database.BlockTx
+		err  error
+	}{
+		{
+			name: "valid",
+			tx:   func(t *testing.T) database.BlockTx { return blockTx(t, pk, chainID, 1, victimID, 100) },
+		},
+		{
+			name: "forged signature",
+			tx: func(t *testing.T) database.BlockTx {
+				return forgedTx(t, 1, senderID, victimID, 815)
+			},
+			err: database.ErrInvalidTransaction,
+		},
+		{
+			name: "wrong chain",
+			tx:   func(t *testing.T) database.BlockTx { return blockTx(t, pk, 2, 1, victimID, 100) },
+			err:  database.ErrInvalidTransaction,
+		},
+		{
+			name: "inflated gas units",
+			tx: func(t *testing.T) database.BlockTx {
+				tx := blockTx(t, pk, chainID, 1, victimID, 100)
+				tx.GasUnit = 50
+				return tx
+			},
+			err: database.ErrInvalidTransaction,
+		},
+		{
+			name: "changed gas price",
+			tx: func(t *testing.T) database.BlockTx {
+				tx := blockTx(t, pk, chainID, 1, victimID, 100)
+				tx.GasPrice = 60
+				return tx
+			},
+			err: database.ErrInvalidTransaction,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			gen := testGenesis(senderID)
+			st := newState(t, gen, memory.New())
+
+			tx := tst.tx(t)
+			block := mineBlock(t, gen, database.Block{}, []database.BlockTx{tx})
+
+			err := st.ProcessProposedBlock(block)
+
+			switch {
+			case tst.err == nil && err != nil:
+				t.Fatalf("Should be able to process the block: %s", err)
+			case tst.err != nil && !errors.Is(err, tst.err):
+				t.Fatalf("Should get the expected error, got %v, exp %v", err, tst.err)
+			}
+
+			if tst.err != nil {
+				if num := st.LatestBlock().Header.Number; num != 0 {
+					t.Fatalf("Should not add the block, got latest block %d", num)
+				}
+				checkBalance(t, st, senderID, balance)
+				return
+			}
+
+			if num := st.LatestBlock().Header.Number; num != 1 {
+				t.Fatalf("Should add the block, got latest block %d", num)
+			}
+			checkBalance(t, st, senderID, balance-tx.Value-gasPrice)
+			checkBalance(t, st, victimID, tx.Value)
+		})
+	}
+}
+
+func TestProcessProposedBlockWriteFailure(t *testing.T) {
+	errDisk := errors.New("disk full")
+
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	block := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, victimID, 100)})
+
+	storage := memory.New()
+	st := newState(t, gen, storage)
+
+	// A block that can't be stored must not change the accounts.
+	storage.FailWrites(errDisk)
+	if err := st.ProcessProposedBlock(block); !errors.Is(err, errDisk) {
+		t.Fatalf("Should get the expected error, got %v, exp %v", err, errDisk)
+	}
+
+	if num := st.LatestBlock().Header.Number; num != 0 {
+		t.Fatalf("Should not add the block, got latest block %d", num)
+	}
+	checkBalance(t, st, senderID, balance)
+
+	// The same block is accepted once the storage recovers.
+	storage.FailWrites(nil)
+	if err := st.ProcessProposedBlock(block); err != nil {
+		t.Fatalf("Should be able to process the block: %s", err)
+	}
+
+	checkBalance(t, st, senderID, balance-100-gasPrice)
+	checkBalance(t, st, victimID, 100)
+}
+
+func TestProcessProposedBlockForgedHeader(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	mined := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, victimID, 100)})
+
+	tt := []struct {
+		name      string
+		header    func(h database.BlockHeader) database.BlockHeader
+		encodable bool
+	}{
+		{
+			// A negative signature value can't be encoded, which must not
+			// leave the block with a hash that solves any difficulty.
+			name: "unencodable header",
+			header: func(h database.BlockHeader) database.BlockHeader {
+				h.Difficulty = 60
+				h.Nonce = 0
+				h.R = big.NewInt(-1)
+				return h
+			},
+		},
+		{
+			name: "difficulty beyond the hash length",
+			header: func(h database.BlockHeader) database.BlockHeader {
+				h.Difficulty = 65
+				return h
+			},
+			encodable: true,
+		},
+		{
+			name: "signed proof of work",
+			header: func(h database.BlockHeader) database.BlockHeader {
+				h.R, h.S, h.V = big.NewInt(1), big.NewInt(1), big.NewInt(27)
+				return solveHeader(h)
+			},
+			encodable: true,
+		},
+		{
+			name: "timestamp in the future",
+			header: func(h database.BlockHeader) database.BlockHeader {
+				h.TimeStamp = uint64(time.Now().Add(24 * time.Hour).UnixMilli())
+				return solveHeader(h)
+			},
+			encodable: true,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			st := newState(t, gen, memory.New())
+
+			block := database.Block{
+				Header:     tst.header(mined.Header),
+				MerkleTree: mined.MerkleTree,
+			}
+
+			blockData := database.BlockData{Header: block.Header, Trans: block.MerkleTree.Values()}
+			_, err := database.ToBlock(blockData)
+
+			switch {
+			case tst.encodable && err != nil:
+				t.Fatalf("Should be able to convert the block: %s", err)
+			case !tst.encodable && err == nil:
+				t.Fatalf("Should not be able to convert the block")
+			}
+
+			if err := st.ProcessProposedBlock(block); err == nil {
+				t.Fatalf("Should not be able to process the block")
+			}
+
+			if num := st.LatestBlock().Header.Number; num != 0 {
+				t.Fatalf("Should not add the block, got latest block %d", num)
+			}
+		})
+	}
+}
+
+func TestQueryCorruptBlock(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	block := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, victimID, 100)})
+
+	storage := memory.New()
+	st := newState(t, gen, storage)
+	if err := st.ProcessProposedBlock(block); err != nil {
+		t.Fatalf("Should be able to process the block: %s", err)
+	}
+
+	storage.CorruptBlock(1, []byte("{ not a block"))
+
+	if _, err := st.QueryBlocksByAccount(senderID); err == nil {
+		t.Fatalf("Should not be able to query blocks by account with a corrupt block")
+	}
+
+	if _, err := st.QueryBlocksByNumber(1, 1); err == nil {
+		t.Fatalf("Should not be able to query blocks by number with a corrupt block")
+	}
+
+	// A node restarting on the corrupt storage must refuse to start.
+	if _, err := state.New(state.Config{
+		BeneficiaryID:  minerID,
+		Storage:        storage,
+		Genesis:        gen,
+		SelectStrategy: "tip",
+		Consensus:      state.ConsensusPOW,
+	}); err == nil {
+		t.Fatalf("Should not be able to construct the state with a corrupt block")
+	}
+}
+
+// =============================================================================
+
+func mustKey(t *testing.T, hexKey string) *ecdsa.PrivateKey {
+	pk, err := crypto.HexToECDSA(hexKey)
+	if err != nil {
+		t.Fatalf("Should be able to load the private key: %s", err)
+	}
+
+	return pk
+}
+
+// testGenesis funds the sender and uses the lowest difficulty so blocks can
+// be mined quickly.
+func testGenesis(senderID database.AccountID) genesis.Genesis {
+	return genesis.Genesis{
+		ChainID:       int16(chainID),
+		TransPerBlock: 10,
+		Difficulty:    1,
+		MiningReward:  700,
+		GasPrice:      int64(gasPrice),
+		Balances:      map[string]uint64{string(senderID): balance},
+	}
+}
+
+func newState(t *testing.T, gen genesis.Genesis, storage database.Storage) *state.State {
+	st, err := state.New(state.Config{
+		BeneficiaryID:  minerID,
+		Host:           "0.0.0.0:9080",
+		Storage:        storage,
+		Genesis:        gen,
+		SelectStrategy: "tip",
+		Consensus:      state.ConsensusPOW,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to construct the state: %s", err)
+	}
+
+	return st
+}
+
+// blockTx signs a transaction from the sender and charges the gas set by
+// the test genesis.
+func blockTx(t *testing.T, pk *ecdsa.PrivateKey, chainID uint16, nonce uint64, toID database.AccountID, value uint64) database.BlockTx {
+	tx, err := database.NewTx(chainID, nonce, database.PublicKeyToAccountID(pk.PublicKey), toID, value, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	signedTx, err := tx.Sign(pk)
+	if err != nil {
+		t.Fatalf("Should be able to sign the transaction: %s", err)
+	}
+
+	return database.NewBlockTx(signedTx, gasPrice, database.GasUnitsPerTx)
+}
+
+// forgedTx moves value out of the from account without its key.
+func forgedTx(t *testing.T, nonce uint64, fromID database.AccountID, toID database.AccountID, value uint64) database.BlockTx {
+	tx, err := database.NewTx(chainID, nonce, fromID, toID, value, 0, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the transaction: %s", err)
+	}
+
+	signedTx := database.SignedTx{Tx: tx, R: big.NewInt(1), S: big.NewInt(1), V: big.NewInt(27)}
+
+	return database.NewBlockTx(signedTx, gasPrice, database.GasUnitsPerTx)
+}
+
+// mineBlock mines a block on top of the parent. The state root is calculated
+// by replaying the parent's chain from the genesis.
+func mineBlock(t *testing.T, gen genesis.Genesis, parent database.Block, trans []database.BlockTx, chain ...database.Block) database.Block {
+	block, err := database.POW(context.Background(), database.POWArgs{
+		BeneficiaryID: minerID,
+		Difficulty:    uint16(gen.Difficulty),
+		MiningReward:  uint64(gen.MiningReward),
+		PrevBlock:     parent,
+		StateRoot:     stateRoot(t, gen, chain...),
+		Trans:         trans,
+	})
+	if err != nil {
+		t.Fatalf("Should be able to mine the block: %s", err)
+	}
+
+	return block
+}
+
+// solveHeader finds a nonce that solves the header's difficulty again after
+// the header was changed.
+func solveHeader(h database.BlockHeader) database.BlockHeader {
+	for !strings.HasPrefix(database.Block{Header: h}.Hash(), "0x"+strings.Repeat("0", int(h.Difficulty))) {
+		h.Nonce++
+	}
+
+	return h
+}
+
+// stateRoot calculates the state root after applying the chain to the
+// genesis.
+func stateRoot(t *testing.T, gen genesis.Genesis, chain ...database.Block) string {
+	storage := memory.New()
+	for _, block := range chain {
+		if err := storage.Write(database.NewBlockData(block)); err != nil {
+			t.Fatalf("Should be able to write the block: %s", err)
+		}
+	}
+
+	db, err := database.New(gen, storage, nil)
+	if err != nil {
+		t.Fatalf("Should be able to construct the database: %s", err)
+	}
+
+	return db.HashState()
+}
+
+func checkBalance(t *testing.T, st *state.State, accountID database.AccountID, exp uint64) {
+	account, err := st.QueryAccount(accountID)
+	if err != nil {
+		t.Fatalf("Should be able to query account %s: %s", accountID, err)
+	}
+
+	if account.Balance != exp {
+		t.Fatalf("Should have the expected balance for %s, got %d, exp %d", accountID, account.Balance, exp)
+	}
+}
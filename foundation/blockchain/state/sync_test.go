@@ -0,0 +1,176 @@
+package state_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+)
+
+func TestNetRequestPeerBlocks(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	// Both chains share the first block and differ in the transaction of
+	// the second block.
+	block1 := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, minerID, 10)})
+
+	tt := []struct {
+		name   string
+		tx     func(t *testing.T) database.BlockTx
+		latest uint64
+		err    error
+	}{
+		{
+			name:   "valid",
+			tx:     func(t *testing.T) database.BlockTx { return blockTx(t, pk, chainID, 2, victimID, 100) },
+			latest: 2,
+		},
+		{
+			name:   "forged signature",
+			tx:     func(t *testing.T) database.BlockTx { return forgedTx(t, 2, senderID, victimID, 815) },
+			latest: 1,
+			err:    database.ErrInvalidTransaction,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			tx := tst.tx(t)
+			block2 := mineBlock(t, gen, block1, []database.BlockTx{tx}, block1)
+
+			pr := newPeer(t, gen, []database.Block{block1, block2})
+			st := newState(t, gen, memory.New())
+
+			err := st.NetRequestPeerBlocks(pr)
+
+			switch {
+			case tst.err == nil && err != nil:
+				t.Fatalf("Should be able to sync the blocks: %s", err)
+			case tst.err != nil && !errors.Is(err, tst.err):
+				t.Fatalf("Should get the expected error, got %v, exp %v", err, tst.err)
+			}
+
+			if num := st.LatestBlock().Header.Number; num != tst.latest {
+				t.Fatalf("Should sync to the expected block, got %d, exp %d", num, tst.latest)
+			}
+
+			if tst.err != nil {
+				checkBalance(t, st, senderID, balance-10-gasPrice)
+				if _, err := st.QueryAccount(victimID); !errors.Is(err, database.ErrUnknownAccount) {
+					t.Fatalf("Should not credit the victim account, got %v", err)
+				}
+			}
+		})
+	}
+}
+
+func TestNetRequestPeerBlocksGenesisMismatch(t *testing.T) {
+	pk := mustKey(t, senderKey)
+	senderID := database.PublicKeyToAccountID(pk.PublicKey)
+	gen := testGenesis(senderID)
+
+	block1 := mineBlock(t, gen, database.Block{}, []database.BlockTx{blockTx(t, pk, chainID, 1, minerID, 10)})
+
+	// The peer runs a chain with a different genesis.
+	other := gen
+	other.MiningReward++
+	pr := newPeer(t, other, []database.Block{block1})
+
+	st := newState(t, gen, memory.New())
+	if err := st.NetRequestPeerBlocks(pr); err == nil {
+		t.Fatalf("Should not be able to sync from a peer with a different genesis")
+	}
+
+	if num := st.LatestBlock().Header.Number; num != 0 {
+		t.Fatalf("Should not sync any blocks, got latest block %d", num)
+	}
+}
+
+// =============================================================================
+
+// newPeer starts a node that serves the specified blocks through the
+// private block list route. The route accepts a block number or "latest"
+// as the end of the range. Like a real node, requests from a node running
+// a different genesis are refused.
+func newPeer(t *testing.T, gen genesis.Genesis, blocks []database.Block) peer.Peer {
+	var latest uint64
+	if len(blocks) > 0 {
+		latest = blocks[len(blocks)-1].Header.Number
+	}
+
+	return startPeer(t, gen, latest, func(from uint64, last uint64) []database.Block {
+		var resp []database.Block
+		for _, block := range blocks {
+			if block.Header.Number >= from && block.Header.Number <= last {
+				resp = append(resp, block)
+			}
+		}
+
+		return resp
+	})
+}
+
+// startPeer starts a node that reports the latest block number through the
+// status route and answers the block list route with the blocks returned by
+// the list function.
+func startPeer(t *testing.T, gen genesis.Genesis, latest uint64, list func(from uint64, last uint64) []database.Block) peer.Peer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/node/status", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(peer.PeerStatus{
+			GenesisHash:       gen.Hash(),
+			LatestBlockNumber: latest,
+		})
+	})
+	mux.HandleFunc("/v1/node/block/list/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(peer.GenesisHashHeader) != gen.Hash() {
+			http.Error(w, "genesis mismatch", http.StatusNotAcceptable)
+			return
+		}
+
+		var from uint64
+		var to string
+		if _, err := fmt.Sscanf(strings.TrimPrefix(r.URL.Path, "/v1/node/block/list/"), "%d/%s", &from, &to); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		last := uint64(math.MaxUint64)
+		if to != "latest" {
+			n, err := strconv.ParseUint(to, 10, 64)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			last = n
+		}
+
+		var resp []database.BlockData
+		for _, block := range list(from, last) {
+			resp = append(resp, database.NewBlockData(block))
+		}
+
+		if len(resp) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		json.NewEncoder(w).Encode(resp)
+	})
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return peer.New(strings.TrimPrefix(srv.URL, "http://"))
+}
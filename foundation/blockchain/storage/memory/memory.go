@@ -0,0 +1,159 @@
+// Package memory implements the ability to read and write blocks to memory.
+// This is useful for tests, fuzzers and throw away networks where nothing
+// should be written to disk.
+package memory
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// Memory represents the serialization implementation for reading and storing
+// blocks in memory. Blocks are kept in their serialized form so they go
+// through the same encoding as the disk implementation. This implements the
+// database.Storage interface.
+type Memory struct {
+	mu       sync.RWMutex
+	blocks   map[uint64][]byte
+	writeErr error
+}
+
+// New constructs a Memory value for use.
+func New() *Memory {
+	return &Memory{
+		blocks: make(map[uint64][]byte),
+	}
+}
+
+// Close in this implementation has nothing to do since the blocks only
+// live in memory.
+func (m *Memory) Close() error {
+	return nil
+}
+
+// Write takes the specified database block and stores it in memory
+// labeled with the block number.
+func (m *Memory) Write(blockData database.BlockData) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.writeErr != nil {
+		return m.writeErr
+	}
+
+	data, err := json.MarshalIndent(blockData, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	m.blocks[blockData.Header.Number] = data
+
+	return nil
+}
+
+// GetBlock locates and returns the contents of the specified block by
+// number. Like the disk implementation, an error wrapping fs.ErrNotExist
+// is returned when the block doesn't exist.
+func (m *Memory) GetBlock(num uint64) (database.BlockData, error) {
+	m.mu.RLock()
+	data, exists := m.blocks[num]
+	m.mu.RUnlock()
+
+	if !exists {
+		return database.BlockData{}, fmt.Errorf("block %d: %w", num, fs.ErrNotExist)
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(data, &blockData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	return blockData, nil
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (m *Memory) ForEach() database.Iterator {
+	return &memoryIterator{storage: m}
+}
+
+// Reset will clear out the blockchain in memory. Any injected failures
+// are left in place.
+func (m *Memory) Reset() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocks = make(map[uint64][]byte)
+
+	return nil
+}
+
+// Rollback removes every block after the specified block number.
+func (m *Memory) Rollback(num uint64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for n := range m.blocks {
+		if n > num {
+			delete(m.blocks, n)
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// FailWrites makes every call to Write return the specified error. Passing
+// nil clears the failure and allows writes again.
+func (m *Memory) FailWrites(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.writeErr = err
+}
+
+// CorruptBlock replaces the serialized form of the specified block with the
+// provided data so reading the block fails or returns unexpected values.
+func (m *Memory) CorruptBlock(num uint64, data []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.blocks[num] = data
+}
+
+// =============================================================================
+
+// memoryIterator represents the iteration implementation for walking
+// through and reading blocks in memory. This implements the database
+// Iterator interface.
+type memoryIterator struct {
+	storage *Memory // Access to the storage API.
+	current uint64  // Current block number being iterated over.
+	eoc     bool    // Represents the iterator is at the end of the chain.
+}
+
+// Next retrieves the next block from memory.
+func (mi *memoryIterator) Next() (database.BlockData, error) {
+	if mi.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	mi.current++
+	blockData, err := mi.storage.GetBlock(mi.current)
+	if errors.Is(err, fs.ErrNotExist) {
+		mi.eoc = true
+	}
+
+	return blockData, err
+}
+
+// Done returns the end of chain value.
+func (mi *memoryIterator) Done() bool {
+	return mi.eoc
+}
@@ -0,0 +1,329 @@
+// Package segment implements the ability to read and write blocks to a
+// single append-only file on disk. Each block is written as a length
+// prefixed and checksummed record and an in-memory index of file offsets
+// provides random access by block number.
+package segment
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+)
+
+// fileName is the name of the segment file inside the database path.
+const fileName = "blocks.log"
+
+// headerSize is the size of the record header that precedes every block.
+// The header contains the block number, the length of the payload and a
+// checksum covering the block number and payload.
+//
+//	[ number 8 bytes ][ length 4 bytes ][ crc32 4 bytes ][ payload ]
+const headerSize = 16
+
+// maxPayload is a sanity check on the length read from a record header so
+// a corrupt length doesn't cause a huge allocation.
+const maxPayload = 64 << 20
+
+// crcTable is the table used to calculate record checksums.
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// ErrCorrupt is returned when a record in the segment file fails its checks
+// and the damage can't be explained by a torn write at the tail.
+var ErrCorrupt = errors.New("segment corrupt")
+
+// Segment represents the serialization implementation for reading and storing
+// blocks in a single append-only file on disk. This implements the
+// database.Storage interface.
+type Segment struct {
+	mu    sync.RWMutex
+	file  *os.File
+	size  int64
+	index map[uint64]int64
+}
+
+// New constructs a Segment value for use. The segment file is opened, or
+// created, inside the specified path and the index is rebuilt by scanning
+// the records. A torn write at the tail of the file, left behind by a crash,
+// is truncated away. Any other damage returns an error wrapping ErrCorrupt.
+func New(dbPath string) (*Segment, error) {
+	if err := os.MkdirAll(dbPath, 0755); err != nil {
+		return nil, err
+	}
+
+	f, err := os.OpenFile(filepath.Join(dbPath, fileName), os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+
+	s := Segment{
+		file:  f,
+		index: make(map[uint64]int64),
+	}
+
+	if err := s.recover(); err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// Close closes the segment file.
+func (s *Segment) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.file.Close()
+}
+
+// Write takes the specified database block and appends it to the end of
+// the segment file. Writing a block number that already exists replaces
+// the block in the index.
+func (s *Segment) Write(blockData database.BlockData) error {
+	payload, err := json.Marshal(blockData)
+	if err != nil {
+		return err
+	}
+
+	if len(payload) > maxPayload {
+		return fmt.Errorf("block %d is too large: %d bytes", blockData.Header.Number, len(payload))
+	}
+
+	// Construct the full record so it's written with a single call.
+	record := make([]byte, headerSize+len(payload))
+	binary.BigEndian.PutUint64(record[0:8], blockData.Header.Number)
+	binary.BigEndian.PutUint32(record[8:12], uint32(len(payload)))
+	copy(record[headerSize:], payload)
+	binary.BigEndian.PutUint32(record[12:16], checksum(record[0:8], payload))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.file.WriteAt(record, s.size); err != nil {
+		return err
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	s.index[blockData.Header.Number] = s.size
+	s.size += int64(len(record))
+
+	return nil
+}
+
+// GetBlock uses the index to locate and return the contents of the
+// specified block by number. Like the disk implementation, an error
+// wrapping fs.ErrNotExist is returned when the block doesn't exist.
+func (s *Segment) GetBlock(num uint64) (database.BlockData, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	offset, exists := s.index[num]
+	if !exists {
+		return database.BlockData{}, fmt.Errorf("block %d: %w", num, fs.ErrNotExist)
+	}
+
+	_, payload, err := s.readRecord(offset)
+	if err != nil {
+		return database.BlockData{}, err
+	}
+
+	var blockData database.BlockData
+	if err := json.Unmarshal(payload, &blockData); err != nil {
+		return database.BlockData{}, err
+	}
+
+	return blockData, nil
+}
+
+// ForEach returns an iterator to walk through all the blocks
+// starting with block number 1.
+func (s *Segment) ForEach() database.Iterator {
+	return &segmentIterator{storage: s}
+}
+
+// Reset will clear out the blockchain on disk.
+func (s *Segment) Reset() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.file.Truncate(0); err != nil {
+		return err
+	}
+
+	s.size = 0
+	s.index = make(map[uint64]int64)
+
+	return s.file.Sync()
+}
+
+// Rollback removes every block after the specified block number. Blocks are
+// appended in order so the file is truncated at the first record belonging
+// to a removed block.
+func (s *Segment) Rollback(num uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cut := s.size
+	for n, offset := range s.index {
+		if n > num && offset < cut {
+			cut = offset
+		}
+	}
+
+	// A block being kept can't live past the truncation point.
+	for n, offset := range s.index {
+		if n <= num && offset >= cut {
+			return fmt.Errorf("block %d is stored after block %d", n, num+1)
+		}
+	}
+
+	if err := s.file.Truncate(cut); err != nil {
+		return err
+	}
+
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	for n := range s.index {
+		if n > num {
+			delete(s.index, n)
+		}
+	}
+	s.size = cut
+
+	return nil
+}
+
+// =============================================================================
+
+// recover scans the segment file from the beginning to rebuild the index.
+// Write appends a record with a single write, so a crash can only damage the
+// last record. When the bad record runs to the end of the file the file is
+// truncated at that point. A bad record followed by more data can't be a
+// torn write and is reported as corruption, and I/O errors are returned so
+// good blocks are never truncated away.
+func (s *Segment) recover() error {
+	info, err := s.file.Stat()
+	if err != nil {
+		return err
+	}
+	fileSize := info.Size()
+
+	var offset int64
+	for offset < fileSize {
+		num, payload, err := s.readRecord(offset)
+		if err != nil {
+			end := offset + headerSize + int64(len(payload))
+
+			switch {
+			case errors.Is(err, io.ErrUnexpectedEOF):
+			case errors.Is(err, ErrCorrupt) && payload != nil && end == fileSize:
+			default:
+				return err
+			}
+
+			if err := s.file.Truncate(offset); err != nil {
+				return fmt.Errorf("truncating torn write at offset %d: %w", offset, err)
+			}
+			if err := s.file.Sync(); err != nil {
+				return err
+			}
+			break
+		}
+
+		s.index[num] = offset
+		offset += int64(headerSize + len(payload))
+	}
+
+	s.size = offset
+
+	return nil
+}
+
+// readRecord reads and validates the record at the specified offset. A
+// record cut short by the end of the file returns an error wrapping
+// io.ErrUnexpectedEOF. A record that fails its checks returns an error
+// wrapping ErrCorrupt, on a checksum failure the payload is returned with
+// the error so the caller knows where the record ends.
+func (s *Segment) readRecord(offset int64) (uint64, []byte, error) {
+	header := make([]byte, headerSize)
+	if _, err := s.file.ReadAt(header, offset); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, fmt.Errorf("reading record header at offset %d: %w", offset, err)
+	}
+
+	num := binary.BigEndian.Uint64(header[0:8])
+	length := binary.BigEndian.Uint32(header[8:12])
+	sum := binary.BigEndian.Uint32(header[12:16])
+
+	if length > maxPayload {
+		return 0, nil, fmt.Errorf("record at offset %d has invalid length %d: %w", offset, length, ErrCorrupt)
+	}
+
+	payload := make([]byte, length)
+	if _, err := s.file.ReadAt(payload, offset+headerSize); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return 0, nil, fmt.Errorf("reading record payload at offset %d: %w", offset, err)
+	}
+
+	if checksum(header[0:8], payload) != sum {
+		return 0, payload, fmt.Errorf("record at offset %d failed checksum: %w", offset, ErrCorrupt)
+	}
+
+	return num, payload, nil
+}
+
+// checksum calculates the checksum for a record using the block number
+// and payload.
+func checksum(num []byte, payload []byte) uint32 {
+	sum := crc32.Update(0, crcTable, num)
+	return crc32.Update(sum, crcTable, payload)
+}
+
+// =============================================================================
+
+// segmentIterator represents the iteration implementation for walking
+// through and reading blocks in the segment file. This implements the
+// database Iterator interface.
+type segmentIterator struct {
+	storage *Segment // Access to the storage API.
+	current uint64   // Current block number being iterated over.
+	eoc     bool     // Represents the iterator is at the end of the chain.
+}
+
+// Next retrieves the next block from the segment file.
+func (si *segmentIterator) Next() (database.BlockData, error) {
+	if si.eoc {
+		return database.BlockData{}, errors.New("end of chain")
+	}
+
+	si.current++
+	blockData, err := si.storage.GetBlock(si.current)
+	if errors.Is(err, fs.ErrNotExist) {
+		si.eoc = true
+	}
+
+	return blockData, err
+}
+
+// Done returns the end of chain value.
+func (si *segmentIterator) Done() bool {
+	return si.eoc
+}
@@ -0,0 +1,173 @@
+package segment_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/segment"
+)
+
+func TestRecover(t *testing.T) {
+	tt := []struct {
+		name   string
+		damage func(t *testing.T, path string, size int64)
+		blocks uint64
+		err    error
+	}{
+		{
+			name:   "clean",
+			damage: func(t *testing.T, path string, size int64) {},
+			blocks: 3,
+		},
+		{
+			name:   "torn header",
+			damage: func(t *testing.T, path string, size int64) { appendBytes(t, path, make([]byte, 10)) },
+			blocks: 3,
+		},
+		{
+			name:   "torn payload",
+			damage: func(t *testing.T, path string, size int64) { truncate(t, path, size-5) },
+			blocks: 2,
+		},
+		{
+			name:   "unwritten tail",
+			damage: func(t *testing.T, path string, size int64) { flipByte(t, path, size-1) },
+			blocks: 2,
+		},
+		{
+			name:   "corrupt middle record",
+			damage: func(t *testing.T, path string, size int64) { flipByte(t, path, 20) },
+			err:    segment.ErrCorrupt,
+		},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			dbPath := t.TempDir()
+			path := filepath.Join(dbPath, "blocks.log")
+
+			writeBlocks(t, dbPath, 3)
+			tst.damage(t, path, fileSize(t, path))
+
+			before := fileSize(t, path)
+
+			s, err := segment.New(dbPath)
+			if tst.err != nil {
+				if !errors.Is(err, tst.err) {
+					t.Fatalf("Should get the expected error, got %v, exp %v", err, tst.err)
+				}
+				if size := fileSize(t, path); size != before {
+					t.Fatalf("Should not truncate a corrupt segment, got %d bytes, exp %d", size, before)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Should be able to open the segment: %s", err)
+			}
+
+			checkBlocks(t, s, tst.blocks)
+
+			// The segment must be usable after recovery and survive being
+			// opened again.
+			if err := s.Write(blockData(tst.blocks + 1)); err != nil {
+				t.Fatalf("Should be able to write after recovery: %s", err)
+			}
+			if err := s.Close(); err != nil {
+				t.Fatalf("Should be able to close the segment: %s", err)
+			}
+
+			s, err = segment.New(dbPath)
+			if err != nil {
+				t.Fatalf("Should be able to reopen the segment: %s", err)
+			}
+			defer s.Close()
+
+			checkBlocks(t, s, tst.blocks+1)
+		})
+	}
+}
+
+// =============================================================================
+
+func blockData(num uint64) database.BlockData {
+	return database.BlockData{
+		Hash:   "0x01",
+		Header: database.BlockHeader{Number: num, MiningReward: 700},
+	}
+}
+
+func writeBlocks(t *testing.T, dbPath string, n uint64) {
+	s, err := segment.New(dbPath)
+	if err != nil {
+		t.Fatalf("Should be able to open the segment: %s", err)
+	}
+	defer s.Close()
+
+	for num := uint64(1); num <= n; num++ {
+		if err := s.Write(blockData(num)); err != nil {
+			t.Fatalf("Should be able to write block %d: %s", num, err)
+		}
+	}
+}
+
+// checkBlocks validates the segment holds exactly the blocks 1 through n.
+func checkBlocks(t *testing.T, s *segment.Segment, n uint64) {
+	var count uint64
+	iter := s.ForEach()
+	for blockData, err := iter.Next(); !iter.Done(); blockData, err = iter.Next() {
+		if err != nil {
+			t.Fatalf("Should be able to read block %d: %s", count+1, err)
+		}
+		count++
+		if blockData.Header.Number != count {
+			t.Fatalf("Should get the expected block, got %d, exp %d", blockData.Header.Number, count)
+		}
+	}
+
+	if count != n {
+		t.Fatalf("Should have the expected number of blocks, got %d, exp %d", count, n)
+	}
+}
+
+func fileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Should be able to stat the segment: %s", err)
+	}
+
+	return info.Size()
+}
+
+func truncate(t *testing.T, path string, size int64) {
+	if err := os.Truncate(path, size); err != nil {
+		t.Fatalf("Should be able to truncate the segment: %s", err)
+	}
+}
+
+func appendBytes(t *testing.T, path string, data []byte) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatalf("Should be able to open the segment: %s", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(data); err != nil {
+		t.Fatalf("Should be able to append to the segment: %s", err)
+	}
+}
+
+func flipByte(t *testing.T, path string, offset int64) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Should be able to read the segment: %s", err)
+	}
+
+	data[offset] ^= 0xff
+
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("Should be able to write the segment: %s", err)
+	}
+}
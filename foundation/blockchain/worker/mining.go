@@ -0,0 +1,133 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// CORE NOTE: The mining operation is managed by this function which runs on
+// it's own goroutine. When a startMining signal is received (mainly because a
+// wallet transaction was received) a block is created and then the POW operation
+// starts. This operation can be cancelled if a proposed block is received and
+// is validated.
+
+// miningOperations handles mining.
+func (w *Worker) miningOperations() {
+	w.evHandler("worker: miningOperations: G started")
+	defer w.evHandler("worker: miningOperations: G completed")
+
+	for {
+		select {
+		case <-w.startMining:
+			if !w.isShutdown() {
+				w.runMiningOperation()
+			}
+		case <-w.shut:
+			w.evHandler("worker: miningOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// runMiningOperation takes all the transactions from the mempool and writes a
+// new block to the database.
+func (w *Worker) runMiningOperation() {
+	w.evHandler("worker: runMiningOperation: MINING: started")
+	defer w.evHandler("worker: runMiningOperation: MINING: completed")
+
+	// Make sure there are at least transactions in the mempool.
+	length := w.state.MempoolLength()
+	if length == 0 {
+		w.evHandler("worker: runMiningOperation: MINING: no transactions to mine: Txs[%d]", length)
+		return
+	}
+
+	// Under proof of authority a signer has to wait for its turn to produce
+	// the next block. The transactions stay in the mempool and mining is
+	// signaled again when the turn comes, unless a block shows up first.
+	delay, ok := w.state.ProducerDelay()
+	switch {
+	case !ok:
+		w.evHandler("worker: runMiningOperation: MINING: not a producer of blocks")
+		return
+	case delay > 0:
+		w.evHandler("worker: runMiningOperation: MINING: waiting for turn: delay[%v]", delay)
+		time.AfterFunc(delay, w.SignalStartMining)
+		return
+	}
+
+	// After running a mining operation, check if a new operation should
+	// be signaled again.
+	defer func() {
+		length := w.state.MempoolLength()
+		if length > 0 {
+			w.evHandler("worker: runMiningOperation: MINING: signal new mining operation: Txs[%d]", length)
+			w.SignalStartMining()
+		}
+	}()
+
+	// Drain the cancel mining channel before starting.
+	select {
+	case <-w.cancelMining:
+		w.evHandler("worker: runMiningOperation: MINING: drained cancel channel")
+	default:
+	}
+
+	// Create a context so mining can be cancelled.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Can't return from this function until these G's are complete.
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	// This G exists to cancel the mining operation.
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		select {
+		case <-w.cancelMining:
+			w.evHandler("worker: runMiningOperation: MINING: CANCEL: requested")
+		case <-w.shut:
+			w.evHandler("worker: runMiningOperation: MINING: CANCEL: shutdown")
+		case <-ctx.Done():
+		}
+	}()
+
+	// This G is performing the mining.
+	go func() {
+		defer func() {
+			cancel()
+			wg.Done()
+		}()
+
+		block, err := w.state.MineNewBlock(ctx)
+		if err != nil {
+			switch {
+			case errors.Is(err, state.ErrNoTransactions):
+				w.evHandler("worker: runMiningOperation: MINING: WARNING: no transactions in mempool")
+			case ctx.Err() != nil:
+				w.evHandler("worker: runMiningOperation: MINING: CANCEL: complete")
+			default:
+				w.evHandler("worker: runMiningOperation: MINING: ERROR: %s", err)
+			}
+			return
+		}
+
+		// WOW, we mined a block. Send the new block to the network.
+		// Log the error, but that's it.
+		if err := w.state.NetSendBlockToPeers(block); err != nil {
+			w.evHandler("worker: runMiningOperation: MINING: sendBlockToPeers: WARNING %s", err)
+		}
+	}()
+
+	// Wait for both G's to terminate.
+	wg.Wait()
+}
@@ -0,0 +1,122 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/peer"
+)
+
+// CORE NOTE: The p2p network is managed by this goroutine. On an interval
+// every known peer is asked for its status so this node can tell when it
+// has fallen behind the rest of the network. The status also carries the
+// peers each node knows about, which is how new nodes are discovered.
+
+// peerOperations handles finding new peers and polling their status.
+func (w *Worker) peerOperations() {
+	w.evHandler("worker: peerOperations: G started")
+	defer w.evHandler("worker: peerOperations: G completed")
+
+	// On startup let the known peers know this node is available. The
+	// status of the peers was already requested by Sync.
+	w.state.NetSendNodeAvailableToPeers()
+
+	for {
+		select {
+		case <-w.ticker.C:
+			if !w.isShutdown() {
+				w.runPeersOperation()
+			}
+		case <-w.peerSync:
+			if !w.isShutdown() {
+				w.runPeersOperation()
+			}
+		case <-w.shut:
+			w.evHandler("worker: peerOperations: received shut signal")
+			return
+		}
+	}
+}
+
+// runPeersOperation requests the status of every known peer and updates
+// the known peer list. Peers that keep failing to respond are dropped.
+func (w *Worker) runPeersOperation() {
+	w.evHandler("worker: runPeersOperation: started")
+	defer w.evHandler("worker: runPeersOperation: completed")
+
+	for _, pr := range w.state.KnownExternalPeers() {
+
+		// Retrieve the status of this peer.
+		peerStatus, err := w.state.NetRequestPeerStatus(pr)
+		if err != nil {
+			w.evHandler("worker: runPeersOperation: NetRequestPeerStatus: %s: ERROR: %s", pr.Host, err)
+
+			if w.state.RecordPeerFailure(pr) {
+				w.evHandler("worker: runPeersOperation: %s: removed after repeated failures", pr.Host)
+			}
+			continue
+		}
+		w.state.RecordPeerSuccess(pr)
+
+		// Refuse to work with a peer running a different genesis.
+		if peerStatus.GenesisHash != w.state.GenesisHash() {
+			w.evHandler("worker: runPeersOperation: %s: WARNING: genesis mismatch: peer[%s]: ours[%s]", pr.Host, peerStatus.GenesisHash, w.state.GenesisHash())
+			w.state.RemoveKnownPeer(pr)
+			continue
+		}
+
+		// Add peers from this node's peer list that we don't know about.
+		w.addNewPeers(peerStatus.KnownPeers)
+
+		latest := w.state.LatestBlock()
+		switch {
+
+		// If this peer has blocks we don't have, we need to add them. A
+		// block that doesn't link to our chain means we are on a fork.
+		case peerStatus.LatestBlockNumber > latest.Header.Number:
+			w.evHandler("worker: runPeersOperation: peer %s is ahead: latest-blknum[%d]: our-blknum[%d]", pr.Host, peerStatus.LatestBlockNumber, latest.Header.Number)
+
+			err := w.state.NetRequestPeerBlocks(pr)
+			switch {
+			case errors.Is(err, database.ErrChainForked):
+				w.resolveFork(pr)
+			case err != nil:
+				w.evHandler("worker: runPeersOperation: NetRequestPeerBlocks: %s: ERROR: %s", pr.Host, err)
+			}
+
+		// The peer is at the same height on a different chain. The peer's
+		// chain may have more work.
+		case peerStatus.LatestBlockNumber == latest.Header.Number && peerStatus.LatestBlockHash != latest.Hash():
+			w.evHandler("worker: runPeersOperation: peer %s is on a different chain: latest-blknum[%d]", pr.Host, peerStatus.LatestBlockNumber)
+			w.resolveFork(pr)
+		}
+	}
+}
+
+// resolveFork asks the state to compare our chain with the chain of the
+// specified peer and switch to it if it has more work.
+func (w *Worker) resolveFork(pr peer.Peer) {
+	if err := w.state.NetResolveFork(pr); err != nil {
+		w.evHandler("worker: runPeersOperation: NetResolveFork: %s: ERROR: %s", pr.Host, err)
+	}
+}
+
+// addNewPeers takes the list of known peers from a peer and adds any that
+// this node doesn't know about. Newly found peers are told this node is
+// available so they learn about this node as well.
+func (w *Worker) addNewPeers(knownPeers []peer.Peer) {
+	w.evHandler("worker: runPeersOperation: addNewPeers: started")
+	defer w.evHandler("worker: runPeersOperation: addNewPeers: completed")
+
+	var added bool
+	for _, pr := range knownPeers {
+		if w.state.AddKnownPeer(pr) {
+			w.evHandler("worker: runPeersOperation: addNewPeers: adding peer-node %s", pr.Host)
+			added = true
+		}
+	}
+
+	if added {
+		w.state.NetSendNodeAvailableToPeers()
+	}
+}
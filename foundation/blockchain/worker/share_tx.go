@@ -0,0 +1,23 @@
+package worker
+
+// CORE NOTE: Transactions received from wallets are queued and shared with
+// the known peers by this goroutine. This keeps the wallet request from
+// waiting on the network and allows every node to mine the transaction.
+
+// shareTxOperations handles sharing new user transactions.
+func (w *Worker) shareTxOperations() {
+	w.evHandler("worker: shareTxOperations: G started")
+	defer w.evHandler("worker: shareTxOperations: G completed")
+
+	for {
+		select {
+		case tx := <-w.txSharing:
+			if !w.isShutdown() {
+				w.state.NetSendTxToPeers(tx)
+			}
+		case <-w.shut:
+			w.evHandler("worker: shareTxOperations: received shut signal")
+			return
+		}
+	}
+}
@@ -0,0 +1,17 @@
+package worker
+
+// CORE NOTE: A node that starts behind the rest of the network can't mine
+// on top of a stale chain. Before any of the operational goroutines are
+// started, the known peers are asked for their status and any missing
+// blocks are downloaded, validated and applied.
+
+// Sync updates the peer list and the blockchain from the known peers.
+func (w *Worker) Sync() {
+	w.evHandler("worker: sync: started")
+	defer w.evHandler("worker: sync: completed")
+
+	w.runPeersOperation()
+
+	latest := w.state.LatestBlock()
+	w.evHandler("worker: sync: latest-blknum[%d]: latest-hash[%s]", latest.Header.Number, latest.Hash())
+}
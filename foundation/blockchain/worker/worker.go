@@ -0,0 +1,164 @@
+// Package worker implements mining, peer updates, and transaction sharing for
+// the blockchain.
+package worker
+
+import (
+	"sync"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+)
+
+// peerUpdateInterval represents the interval of finding new peer nodes
+// and updating the blockchain on disk with missing blocks.
+const peerUpdateInterval = time.Minute
+
+// maxTxShareRequests represents the max number of pending tx network share
+// requests that can be outstanding before share requests are dropped. To keep
+// this simple, a buffered channel of this arbitrary number is being used. If
+// the channel does become full, requests for new transactions to be shared
+// will not be accepted.
+const maxTxShareRequests = 100
+
+// =============================================================================
+
+// Worker manages the POW workflows for the blockchain.
+type Worker struct {
+	state        *state.State
+	wg           sync.WaitGroup
+	ticker       *time.Ticker
+	shut         chan struct{}
+	startMining  chan bool
+	cancelMining chan bool
+	peerSync     chan bool
+	txSharing    chan database.BlockTx
+	evHandler    state.EventHandler
+}
+
+// Run creates a worker, registers the worker with the state package, and
+// starts up all the background processes.
+func Run(st *state.State, evHandler state.EventHandler) {
+	w := Worker{
+		state:        st,
+		ticker:       time.NewTicker(peerUpdateInterval),
+		shut:         make(chan struct{}),
+		startMining:  make(chan bool, 1),
+		cancelMining: make(chan bool, 1),
+		peerSync:     make(chan bool, 1),
+		txSharing:    make(chan database.BlockTx, maxTxShareRequests),
+		evHandler:    evHandler,
+	}
+
+	// Register this worker with the state package.
+	st.Worker = &w
+
+	// Catch up with the network before mining. Any blocks the known peers
+	// have that this node is missing are downloaded and applied.
+	w.Sync()
+
+	// Load the set of operations we need to run.
+	operations := []func(){
+		w.peerOperations,
+		w.miningOperations,
+		w.shareTxOperations,
+	}
+
+	// Set waitgroup to match the number of G's we need for the set
+	// of operations we have.
+	g := len(operations)
+	w.wg.Add(g)
+
+	// We don't want to return until we know all the G's are up and running.
+	hasStarted := make(chan bool)
+
+	// Start all the operational G's.
+	for _, op := range operations {
+		go func(op func()) {
+			defer w.wg.Done()
+			hasStarted <- true
+			op()
+		}(op)
+	}
+
+	// Wait for the G's to report they are running.
+	for i := 0; i < g; i++ {
+		<-hasStarted
+	}
+
+	// Mine anything that was left in the mempool.
+	w.SignalStartMining()
+}
+
+// =============================================================================
+// These methods implement the state.Worker interface.
+
+// Shutdown terminates the goroutine performing work.
+func (w *Worker) Shutdown() {
+	w.evHandler("worker: shutdown: started")
+	defer w.evHandler("worker: shutdown: completed")
+
+	w.evHandler("worker: shutdown: stop ticker")
+	w.ticker.Stop()
+
+	// Closing the shut channel also cancels any mining in progress. A signal
+	// on the cancel mining channel can be drained by a mining operation that
+	// is just starting, closing a channel can't be missed.
+	w.evHandler("worker: shutdown: terminate goroutines")
+	close(w.shut)
+	w.wg.Wait()
+}
+
+// SignalStartMining starts a mining operation. If there is already a signal
+// pending in the channel, just return since a mining operation will start.
+func (w *Worker) SignalStartMining() {
+	select {
+	case w.startMining <- true:
+	default:
+	}
+	w.evHandler("worker: SignalStartMining: mining signaled")
+}
+
+// SignalCancelMining signals the G executing the runMiningOperation function
+// to stop immediately.
+func (w *Worker) SignalCancelMining() {
+	select {
+	case w.cancelMining <- true:
+	default:
+	}
+	w.evHandler("worker: SignalCancelMining: MINING: CANCEL: signaled")
+}
+
+// SignalShareTx signals a share transaction operation. If
+// maxTxShareRequests signals exist in the channel, we won't send these.
+func (w *Worker) SignalShareTx(blockTx database.BlockTx) {
+	select {
+	case w.txSharing <- blockTx:
+		w.evHandler("worker: SignalShareTx: share Tx signaled")
+	default:
+		w.evHandler("worker: SignalShareTx: queue full, transactions won't be shared.")
+	}
+}
+
+// SignalPeerSync signals the G executing the peerOperations function to
+// request the status of the peers now instead of waiting for the ticker. If
+// there is already a signal pending in the channel, just return.
+func (w *Worker) SignalPeerSync() {
+	select {
+	case w.peerSync <- true:
+	default:
+	}
+	w.evHandler("worker: SignalPeerSync: peer sync signaled")
+}
+
+// =============================================================================
+
+// isShutdown is used to test if a shutdown has been signaled.
+func (w *Worker) isShutdown() bool {
+	select {
+	case <-w.shut:
+		return true
+	default:
+		return false
+	}
+}
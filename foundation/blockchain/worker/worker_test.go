@@ -0,0 +1,110 @@
+package worker_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/blockchain/foundation/blockchain/database"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/genesis"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/state"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/storage/memory"
+	"github.com/ardanlabs/blockchain/foundation/blockchain/worker"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const (
+	senderKey = "9f332e3700d8fc2446eaf6d15034cf96e0c2745e40353deef032a5dbf1dfed93"
+	victimID  = database.AccountID("0xdd6B972ffcc631a62CAE1BB9d80b7ff429c8ebA4")
+	minerID   = database.AccountID("0xFef311483Cc040e1A89fb9bb469eeB8A70935EF8")
+)
+
+func TestShutdownWhileMining(t *testing.T) {
+	tt := []struct {
+		name      string
+		waitUntil string
+	}{
+		{name: "mining starting"},
+		{name: "mining running", waitUntil: "MINING: running"},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			pk, err := crypto.HexToECDSA(senderKey)
+			if err != nil {
+				t.Fatalf("Should be able to load the private key: %s", err)
+			}
+			senderID := database.PublicKeyToAccountID(pk.PublicKey)
+
+			// The highest difficulty makes sure the block is never mined, so
+			// only the shutdown can end the mining operation.
+			gen := genesis.Genesis{
+				ChainID:       1,
+				TransPerBlock: 10,
+				Difficulty:    16,
+				GasPrice:      15,
+				Balances:      map[string]uint64{string(senderID): 1000},
+			}
+
+			// The mining events are raised by the state, so both get the handler.
+			reached := make(chan struct{})
+			ev := func(v string, args ...any) {
+				if tst.waitUntil != "" && strings.Contains(v, tst.waitUntil) {
+					select {
+					case reached <- struct{}{}:
+					default:
+					}
+				}
+			}
+
+			st, err := state.New(state.Config{
+				BeneficiaryID:  minerID,
+				Host:           "0.0.0.0:9080",
+				Storage:        memory.New(),
+				Genesis:        gen,
+				SelectStrategy: "tip",
+				Consensus:      state.ConsensusPOW,
+				EvHandler:      ev,
+			})
+			if err != nil {
+				t.Fatalf("Should be able to construct the state: %s", err)
+			}
+
+			tx, err := database.NewTx(1, 1, senderID, victimID, 100, 0, nil)
+			if err != nil {
+				t.Fatalf("Should be able to construct the transaction: %s", err)
+			}
+
+			signedTx, err := tx.Sign(pk)
+			if err != nil {
+				t.Fatalf("Should be able to sign the transaction: %s", err)
+			}
+
+			if err := st.UpsertWalletTransaction(signedTx); err != nil {
+				t.Fatalf("Should be able to add the transaction: %s", err)
+			}
+
+			worker.Run(st, ev)
+
+			if tst.waitUntil != "" {
+				select {
+				case <-reached:
+				case <-time.After(5 * time.Second):
+					t.Fatalf("Should reach %q", tst.waitUntil)
+				}
+			}
+
+			done := make(chan struct{})
+			go func() {
+				st.Worker.Shutdown()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatalf("Should be able to shutdown while mining")
+			}
+		})
+	}
+}
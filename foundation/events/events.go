@@ -0,0 +1,95 @@
+// Package events allows for the registering and receiving of events. Every
+// event sent is fanned out to all the registered receivers.
+package events
+
+import (
+	"fmt"
+	"sync"
+)
+
+// maxQueuedEvents represents the number of events a receiver can fall behind
+// before new events are dropped for that receiver. This keeps a slow receiver
+// from blocking the sender.
+const maxQueuedEvents = 100
+
+// Events maintains a mapping of unique id and channels so goroutines
+// can register and receive events.
+type Events struct {
+	mu   sync.RWMutex
+	m    map[string]chan string
+	shut bool
+}
+
+// New constructs an events for registering and receiving events.
+func New() *Events {
+	return &Events{
+		m: make(map[string]chan string),
+	}
+}
+
+// Shutdown closes and removes all channels that were provided by
+// the call to Acquire. Any call to Acquire after this returns a closed channel.
+func (evt *Events) Shutdown() {
+	evt.mu.Lock()
+	defer evt.mu.Unlock()
+
+	evt.shut = true
+
+	for id, ch := range evt.m {
+		close(ch)
+		delete(evt.m, id)
+	}
+}
+
+// Acquire takes a unique id and returns a channel that can be used
+// to receive events. Once Shutdown has been called, the channel returned
+// is already closed so the receiver doesn't wait for events forever.
+func (evt *Events) Acquire(id string) chan string {
+	evt.mu.Lock()
+	defer evt.mu.Unlock()
+
+	if evt.shut {
+		ch := make(chan string)
+		close(ch)
+		return ch
+	}
+
+	// Since a message will be dropped if the websocket receiver is
+	// not ready to receive, this arbitrary buffer should give the receiver
+	// enough time to not lose a message. Websocket send could take long.
+	ch := make(chan string, maxQueuedEvents)
+	evt.m[id] = ch
+
+	return ch
+}
+
+// Release closes and removes the channel that was provided by
+// the call to Acquire.
+func (evt *Events) Release(id string) error {
+	evt.mu.Lock()
+	defer evt.mu.Unlock()
+
+	ch, exists := evt.m[id]
+	if !exists {
+		return fmt.Errorf("id %q does not exist", id)
+	}
+
+	delete(evt.m, id)
+	close(ch)
+
+	return nil
+}
+
+// Send signals a message to every registered channel. Send will not block
+// waiting for a receiver on any given channel.
+func (evt *Events) Send(s string) {
+	evt.mu.RLock()
+	defer evt.mu.RUnlock()
+
+	for _, ch := range evt.m {
+		select {
+		case ch <- s:
+		default:
+		}
+	}
+}
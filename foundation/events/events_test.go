@@ -0,0 +1,39 @@
+package events_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/events"
+)
+
+func TestShutdown(t *testing.T) {
+	evts := events.New()
+
+	before := evts.Acquire("before")
+	evts.Send("event")
+
+	if msg := <-before; msg != "event" {
+		t.Fatalf("Should get the expected event, got %q, exp %q", msg, "event")
+	}
+
+	evts.Shutdown()
+
+	tt := []struct {
+		name string
+		ch   chan string
+	}{
+		{name: "acquired before shutdown", ch: before},
+		{name: "acquired after shutdown", ch: evts.Acquire("after")},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			if _, open := <-tst.ch; open {
+				t.Fatalf("Should get a closed channel")
+			}
+		})
+	}
+
+	// Sending after shutdown must not panic on a closed channel.
+	evts.Send("event")
+}
@@ -0,0 +1,328 @@
+// Package keystore provides support for storing private keys encrypted with a
+// password. The format is a JSON envelope modeled after the Ethereum v3
+// keystore, using scrypt to derive the key and AES-GCM for the encryption.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Version is the version of the keystore format.
+const Version = 1
+
+// The scrypt cost parameters. The standard cost uses 256MB of memory and
+// takes around a second on modern hardware. The light cost is meant for
+// tests and throw away keys.
+const (
+	StandardScryptN = 1 << 18
+	LightScryptN    = 1 << 12
+
+	scryptR     = 8
+	scryptP     = 1
+	scryptDKLen = 32
+	saltLen     = 32
+)
+
+// The bounds on the scrypt parameters accepted when decrypting. The
+// parameters are read from the file, so they are checked before running
+// scrypt to keep a bad file from using an unbounded amount of memory or
+// time. Scrypt allocates 128*N*r bytes, so N and r are bounded together by
+// the memory the standard parameters use, 256 MiB. The time grows with p on
+// top of that.
+const (
+	maxScryptMemory = 128 * StandardScryptN * scryptR
+	maxScryptP      = 4
+)
+
+// The names of the algorithms used by this version of the format.
+const (
+	cipherName = "aes-256-gcm"
+	kdfName    = "scrypt"
+)
+
+// ErrDecrypt is returned when the key can't be decrypted, which is
+// normally caused by the wrong password.
+var ErrDecrypt = errors.New("could not decrypt key with given password")
+
+// =============================================================================
+
+// Key represents the encrypted form of a private key as stored on disk.
+type Key struct {
+	Address string     `json:"address"`
+	ID      string     `json:"id"`
+	Version int        `json:"version"`
+	Crypto  CryptoJSON `json:"crypto"`
+}
+
+// CryptoJSON represents the encryption details of the private key.
+type CryptoJSON struct {
+	Cipher       string       `json:"cipher"`
+	CipherText   string       `json:"ciphertext"`
+	CipherParams CipherParams `json:"cipherparams"`
+	KDF          string       `json:"kdf"`
+	KDFParams    KDFParams    `json:"kdfparams"`
+}
+
+// CipherParams represents the parameters used by the cipher.
+type CipherParams struct {
+	Nonce string `json:"nonce"`
+}
+
+// KDFParams represents the parameters used to derive the encryption key
+// from the password.
+type KDFParams struct {
+	N     int    `json:"n"`
+	R     int    `json:"r"`
+	P     int    `json:"p"`
+	DKLen int    `json:"dklen"`
+	Salt  string `json:"salt"`
+}
+
+// =============================================================================
+
+// Encrypt encrypts the private key with the password using the specified
+// scrypt cost. The address of the key is authenticated with the private key
+// so the envelope can't be altered to point to a different account.
+func Encrypt(privateKey *ecdsa.PrivateKey, password string, scryptN int) ([]byte, error) {
+	salt := make([]byte, saltLen)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, fmt.Errorf("reading salt: %w", err)
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("reading nonce: %w", err)
+	}
+
+	address := crypto.PubkeyToAddress(privateKey.PublicKey).Hex()
+	cipherText := gcm.Seal(nil, nonce, crypto.FromECDSA(privateKey), []byte(address))
+
+	key := Key{
+		Address: address,
+		ID:      uuid.NewString(),
+		Version: Version,
+		Crypto: CryptoJSON{
+			Cipher:     cipherName,
+			CipherText: hex.EncodeToString(cipherText),
+			CipherParams: CipherParams{
+				Nonce: hex.EncodeToString(nonce),
+			},
+			KDF: kdfName,
+			KDFParams: KDFParams{
+				N:     scryptN,
+				R:     scryptR,
+				P:     scryptP,
+				DKLen: scryptDKLen,
+				Salt:  hex.EncodeToString(salt),
+			},
+		},
+	}
+
+	return json.MarshalIndent(key, "", "  ")
+}
+
+// Decrypt decrypts the private key in the envelope using the password.
+func Decrypt(data []byte, password string) (*ecdsa.PrivateKey, error) {
+	var key Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("decoding keystore: %w", err)
+	}
+
+	switch {
+	case key.Version != Version:
+		return nil, fmt.Errorf("unsupported keystore version %d", key.Version)
+	case key.Crypto.Cipher != cipherName:
+		return nil, fmt.Errorf("unsupported cipher %q", key.Crypto.Cipher)
+	case key.Crypto.KDF != kdfName:
+		return nil, fmt.Errorf("unsupported kdf %q", key.Crypto.KDF)
+	case !common.IsHexAddress(key.Address):
+		return nil, fmt.Errorf("invalid address %q", key.Address)
+	}
+
+	salt, err := hex.DecodeString(key.Crypto.KDFParams.Salt)
+	if err != nil {
+		return nil, fmt.Errorf("decoding salt: %w", err)
+	}
+
+	nonce, err := hex.DecodeString(key.Crypto.CipherParams.Nonce)
+	if err != nil {
+		return nil, fmt.Errorf("decoding nonce: %w", err)
+	}
+
+	cipherText, err := hex.DecodeString(key.Crypto.CipherText)
+	if err != nil {
+		return nil, fmt.Errorf("decoding ciphertext: %w", err)
+	}
+
+	kdf := key.Crypto.KDFParams
+	switch {
+	case kdf.DKLen != scryptDKLen:
+		return nil, fmt.Errorf("invalid kdf key length %d", kdf.DKLen)
+	case kdf.N < LightScryptN || kdf.N > maxScryptMemory/128 || kdf.N&(kdf.N-1) != 0:
+		return nil, fmt.Errorf("invalid kdf cost n %d", kdf.N)
+	case kdf.R < 1:
+		return nil, fmt.Errorf("invalid kdf block size r %d", kdf.R)
+	case kdf.R > maxScryptMemory/(128*kdf.N):
+		return nil, fmt.Errorf("kdf memory cost too large, n %d, r %d, max %d bytes", kdf.N, kdf.R, maxScryptMemory)
+	case kdf.P < 1 || kdf.P > maxScryptP:
+		return nil, fmt.Errorf("invalid kdf parallelization p %d", kdf.P)
+	}
+
+	derivedKey, err := scrypt.Key([]byte(password), salt, kdf.N, kdf.R, kdf.P, kdf.DKLen)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+
+	gcm, err := newGCM(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("invalid nonce length %d", len(nonce))
+	}
+
+	plainText, err := gcm.Open(nil, nonce, cipherText, []byte(key.Address))
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	privateKey, err := crypto.ToECDSA(plainText)
+	if err != nil {
+		return nil, err
+	}
+
+	if crypto.PubkeyToAddress(privateKey.PublicKey) != common.HexToAddress(key.Address) {
+		return nil, errors.New("decrypted key doesn't match the keystore address")
+	}
+
+	return privateKey, nil
+}
+
+// =============================================================================
+
+// Load reads the keystore file at the specified path and decrypts the
+// private key using the password.
+func Load(path string, password string) (*ecdsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return Decrypt(data, password)
+}
+
+// Save encrypts the private key with the password and writes the keystore
+// file to the specified path. The file is only readable by the owner. The
+// file is written to a temporary file first and renamed over the old file,
+// so a crash never leaves a partially written key behind.
+func Save(path string, privateKey *ecdsa.PrivateKey, password string, scryptN int) error {
+	data, err := Encrypt(privateKey, password, scryptN)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	// CreateTemp creates the file only readable by the owner.
+	f, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	if err := writeFile(f, append(data, '\n')); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// Address reads the keystore file at the specified path and returns the
+// address of the key without decrypting it.
+func Address(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	var key Key
+	if err := json.Unmarshal(data, &key); err != nil {
+		return "", fmt.Errorf("decoding keystore: %w", err)
+	}
+
+	return key.Address, nil
+}
+
+// =============================================================================
+
+// writeFile writes the data to the file and flushes it to disk before the
+// file is closed.
+func writeFile(f *os.File, data []byte) error {
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// syncDir flushes the directory to disk so a rename inside of it is durable.
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+// newGCM constructs the AES-GCM cipher for the derived key.
+func newGCM(derivedKey []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(derivedKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,115 @@
+package keystore_test
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ardanlabs/blockchain/foundation/keystore"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+const password = "kennedy"
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "kennedy.json")
+
+	// Saving over an existing file must replace it.
+	for i := 0; i < 2; i++ {
+		pk, err := crypto.GenerateKey()
+		if err != nil {
+			t.Fatalf("Should be able to generate a key: %s", err)
+		}
+
+		if err := keystore.Save(path, pk, password, keystore.LightScryptN); err != nil {
+			t.Fatalf("Should be able to save the key: %s", err)
+		}
+
+		got, err := keystore.Load(path, password)
+		if err != nil {
+			t.Fatalf("Should be able to load the key: %s", err)
+		}
+
+		if !got.Equal(pk) {
+			t.Fatalf("Should load the saved key")
+		}
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("Should be able to stat the key: %s", err)
+	}
+
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Fatalf("Should only be readable by the owner, got %v", mode)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("Should be able to read the directory: %s", err)
+	}
+
+	if len(entries) != 1 {
+		t.Fatalf("Should not leave temporary files behind, got %d files", len(entries))
+	}
+}
+
+func TestDecryptKDFParams(t *testing.T) {
+	pk, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("Should be able to generate a key: %s", err)
+	}
+
+	data, err := keystore.Encrypt(pk, password, keystore.LightScryptN)
+	if err != nil {
+		t.Fatalf("Should be able to encrypt the key: %s", err)
+	}
+
+	tt := []struct {
+		name   string
+		params func(kdf *keystore.KDFParams)
+		valid  bool
+	}{
+		{name: "valid", params: func(kdf *keystore.KDFParams) {}, valid: true},
+		{name: "short key", params: func(kdf *keystore.KDFParams) { kdf.DKLen = 16 }},
+		{name: "long key", params: func(kdf *keystore.KDFParams) { kdf.DKLen = 64 }},
+		{name: "n not a power of two", params: func(kdf *keystore.KDFParams) { kdf.N = keystore.LightScryptN + 1 }},
+		{name: "n too small", params: func(kdf *keystore.KDFParams) { kdf.N = 2 }},
+		{name: "n too large", params: func(kdf *keystore.KDFParams) { kdf.N = 1 << 30 }},
+		{name: "r zero", params: func(kdf *keystore.KDFParams) { kdf.R = 0 }},
+		{name: "r too large", params: func(kdf *keystore.KDFParams) { kdf.R = 1 << 20 }},
+		{name: "n and r too large together", params: func(kdf *keystore.KDFParams) { kdf.N, kdf.R = 1<<20, 32 }},
+		{name: "p zero", params: func(kdf *keystore.KDFParams) { kdf.P = 0 }},
+		{name: "p too large", params: func(kdf *keystore.KDFParams) { kdf.P = 1 << 20 }},
+	}
+
+	for _, tst := range tt {
+		t.Run(tst.name, func(t *testing.T) {
+			var key keystore.Key
+			if err := json.Unmarshal(data, &key); err != nil {
+				t.Fatalf("Should be able to decode the keystore: %s", err)
+			}
+
+			tst.params(&key.Crypto.KDFParams)
+
+			modified, err := json.Marshal(key)
+			if err != nil {
+				t.Fatalf("Should be able to encode the keystore: %s", err)
+			}
+
+			_, err = keystore.Decrypt(modified, password)
+
+			switch {
+			case tst.valid && err != nil:
+				t.Fatalf("Should be able to decrypt the key: %s", err)
+			case !tst.valid && err == nil:
+				t.Fatalf("Should not be able to decrypt the key with bad kdf params")
+			case !tst.valid && errors.Is(err, keystore.ErrDecrypt):
+				t.Fatalf("Should reject the kdf params before deriving the key")
+			}
+		})
+	}
+}